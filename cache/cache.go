@@ -17,8 +17,8 @@ func CreateCacheService() (*CacheService, error) {
 		return nil, err
 	}
 
-	// Auto-migrate the schema for the Metric model
-	err = db.AutoMigrate(&Metric{})
+	// Auto-migrate the schema for the Metric and VolumeUsage models
+	err = db.AutoMigrate(&Metric{}, &VolumeUsage{})
 	if err != nil {
 		return nil, err
 	}
@@ -65,3 +65,33 @@ func (cache *CacheService) GetMetricsByDevice(device string) *[]Metric {
 	}
 	return &metrics
 }
+
+func (cache *CacheService) AddVolumeUsage(volume string, timestamp int64, usedBytes float64, totalBytes float64) {
+	if err := cache.db.Save(&VolumeUsage{
+		Volume:     volume,
+		Timestamp:  timestamp,
+		UsedBytes:  usedBytes,
+		TotalBytes: totalBytes,
+	}).Error; err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// GetVolumeUsageHistory returns the samples of a volume taken at or after since,
+// oldest first.
+func (cache *CacheService) GetVolumeUsageHistory(volume string, since int64) *[]VolumeUsage {
+	var history []VolumeUsage
+	if err := cache.db.Where("volume = ? AND timestamp >= ?", volume, since).
+		Order("timestamp").
+		Find(&history).Error; err != nil {
+		slog.Error(err.Error())
+	}
+	return &history
+}
+
+// PruneVolumeUsage removes samples older than before for every volume.
+func (cache *CacheService) PruneVolumeUsage(before int64) {
+	if err := cache.db.Where("timestamp < ?", before).Delete(&VolumeUsage{}).Error; err != nil {
+		slog.Error(err.Error())
+	}
+}
@@ -5,3 +5,18 @@ type Metric struct {
 	Metric string `gorm:"primaryKey"`
 	Value  float64
 }
+
+// VolumeUsage is one sample of the used space of a volume. Unlike Metric it
+// survives ClearCache so trends can be computed across collections.
+type VolumeUsage struct {
+	Volume     string `gorm:"primaryKey"`
+	Timestamp  int64  `gorm:"primaryKey"`
+	UsedBytes  float64
+	TotalBytes float64
+}
+
+// AgentState is a persisted key/value pair that has to survive agent restarts.
+type AgentState struct {
+	Key   string `gorm:"primaryKey"`
+	Value string
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// StateService keeps agent state in a SQLite file, unlike CacheService whose
+// database only lives in memory.
+type StateService struct {
+	db *gorm.DB
+	// readOnly drops every write, so a dry run sees the saved state without
+	// changing what the agent finds at its next start.
+	readOnly bool
+}
+
+func CreateStateService(path string) (*StateService, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		slog.Error("Failed to open state database", "path", path)
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&AgentState{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &StateService{db: db}, nil
+}
+
+// GetValue returns the stored value and whether the key exists.
+func (state *StateService) GetValue(key string) (string, bool) {
+	var record AgentState
+	err := state.db.Where("key = ?", key).First(&record).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Error(err.Error())
+		}
+		return "", false
+	}
+	return record.Value, true
+}
+
+// SetReadOnly makes SetValue and DeleteValue do nothing.
+func (state *StateService) SetReadOnly() {
+	state.readOnly = true
+}
+
+func (state *StateService) SetValue(key string, value string) {
+	if state.readOnly {
+		return
+	}
+	if err := state.db.Save(&AgentState{Key: key, Value: value}).Error; err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func (state *StateService) DeleteValue(key string) {
+	if state.readOnly {
+		return
+	}
+	if err := state.db.Delete(&AgentState{Key: key}).Error; err != nil {
+		slog.Error(err.Error())
+	}
+}
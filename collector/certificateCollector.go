@@ -0,0 +1,421 @@
+package collector
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"if-win-dex-agent/cache"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+var certificateExtensions = []string{".pem", ".crt", ".cer", ".cert"}
+
+const stateCertificatePrefix = "certificate."
+
+type CertificateCollector struct {
+	config       CertificateConfig
+	stateService *cache.StateService
+	roots        *x509.CertPool
+	// certificates is keyed by endpoint or by file path and index.
+	certificates map[string]certificateStatus
+	levels       map[string]string
+	fingerprints map[string]string
+	collectTime  time.Time
+	events       []Event
+}
+
+func NewCertificateCollector(config CertificateConfig, stateService *cache.StateService) *CertificateCollector {
+	collector := &CertificateCollector{
+		config:       config,
+		stateService: stateService,
+		certificates: make(map[string]certificateStatus),
+		levels:       make(map[string]string),
+		fingerprints: make(map[string]string),
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		slog.Error("Error loading the system root certificates", "error", err)
+		roots = x509.NewCertPool()
+	}
+	if config.RootsFile != "" {
+		if data, err := os.ReadFile(config.RootsFile); err != nil {
+			slog.Error("Error reading roots_file", "path", config.RootsFile, "error", err)
+		} else if !roots.AppendCertsFromPEM(data) {
+			slog.Error("No certificates found in roots_file", "path", config.RootsFile)
+		}
+	}
+	collector.roots = roots
+	return collector
+}
+
+// LoadCertificateConfig reads the [certificate] section, for example:
+//
+//	[certificate]
+//	endpoints = portal.example.com:443, ldap.example.com:636
+//	paths = C:\ProgramData\Certs, C:\App\server.pem
+//	roots_file = C:\ProgramData\Certs\corp-root.pem
+//	warning_days = 30
+//	critical_days = 7
+func LoadCertificateConfig(p *configparser.ConfigParser) CertificateConfig {
+	return CertificateConfig{
+		Endpoints:    getConfigList(p, CertificateSectionName, "endpoints"),
+		Paths:        getConfigList(p, CertificateSectionName, "paths"),
+		RootsFile:    getConfigString(p, CertificateSectionName, "roots_file", ""),
+		Timeout:      getConfigDuration(p, CertificateSectionName, "timeout", 10*time.Second),
+		WarningDays:  getConfigFloat(p, CertificateSectionName, "warning_days", 30),
+		CriticalDays: getConfigFloat(p, CertificateSectionName, "critical_days", 7),
+	}
+}
+
+func (collector *CertificateCollector) Collect() {
+	collector.collectTime = time.Now()
+	certificates := make(map[string]certificateStatus)
+	for _, endpoint := range collector.config.Endpoints {
+		status, err := collector.checkEndpoint(endpoint)
+		if err != nil {
+			slog.Warn("Error checking TLS endpoint certificate", "endpoint", endpoint, "error", err)
+			continue
+		}
+		certificates[endpoint] = status
+	}
+	for _, path := range collector.certificateFiles() {
+		statuses, err := collector.checkFile(path)
+		if err != nil {
+			slog.Warn("Error checking certificate file", "path", path, "error", err)
+			continue
+		}
+		for index, status := range statuses {
+			certificates[fmt.Sprintf("%s#%d", path, index)] = status
+		}
+	}
+	collector.certificates = certificates
+
+	for name, status := range certificates {
+		collector.detectChanges(name, status)
+	}
+}
+
+// checkEndpoint completes a handshake without verification, so expired and
+// untrusted certificates can still be reported, and verifies the chain it got.
+func (collector *CertificateCollector) checkEndpoint(endpoint string) (certificateStatus, error) {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return certificateStatus{}, err
+	}
+	dialer := &net.Dialer{Timeout: collector.config.Timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", endpoint, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	if err != nil {
+		return certificateStatus{}, err
+	}
+	defer conn.Close()
+
+	chain := conn.ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return certificateStatus{}, errors.New("no certificate presented")
+	}
+	status := describeCertificate(chain[0])
+	collector.verify(&status, chain[0], chain[1:], host)
+	return status, nil
+}
+
+// checkFile describes every certificate of a file. When the file is a chain,
+// the leaf is verified with the rest as intermediates and the intermediates
+// are not verified on their own; otherwise, as in a bundle of roots, each
+// certificate is verified alone.
+func (collector *CertificateCollector) checkFile(path string) ([]certificateStatus, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	certificates, err := parseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	chain := isCertificateChain(certificates)
+	statuses := make([]certificateStatus, 0, len(certificates))
+	for index, certificate := range certificates {
+		status := describeCertificate(certificate)
+		switch {
+		case !chain:
+			collector.verify(&status, certificate, nil, "")
+		case index == 0:
+			collector.verify(&status, certificate, certificates[1:], "")
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// isCertificateChain reports whether every certificate is signed by the one
+// following it.
+func isCertificateChain(certificates []*x509.Certificate) bool {
+	if len(certificates) < 2 {
+		return false
+	}
+	for index := range len(certificates) - 1 {
+		if certificates[index].CheckSignatureFrom(certificates[index+1]) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// certificateFiles expands the configured paths, walking directories for
+// files with a certificate extension.
+func (collector *CertificateCollector) certificateFiles() []string {
+	files := make([]string, 0)
+	for _, path := range collector.config.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			slog.Warn("Error reading certificate path", "path", path, "error", err)
+			continue
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		_ = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				for _, extension := range certificateExtensions {
+					if strings.EqualFold(filepath.Ext(file), extension) {
+						files = append(files, file)
+						break
+					}
+				}
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// parseCertificates reads every certificate of a PEM file, or the single
+// certificate of a DER file.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certificates := make([]*x509.Certificate, 0)
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) > 0 {
+		return certificates, nil
+	}
+	certificate, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, errors.New("no certificate found")
+	}
+	return []*x509.Certificate{certificate}, nil
+}
+
+func describeCertificate(certificate *x509.Certificate) certificateStatus {
+	fingerprint := sha256.Sum256(certificate.Raw)
+	return certificateStatus{
+		Subject:     certificate.Subject.String(),
+		Issuer:      certificate.Issuer.String(),
+		Serial:      certificate.SerialNumber.String(),
+		NotAfter:    certificate.NotAfter,
+		KeySize:     publicKeySize(certificate),
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+	}
+}
+
+// verify checks the certificate against the roots with the given
+// intermediates.
+func (collector *CertificateCollector) verify(status *certificateStatus, certificate *x509.Certificate, intermediates []*x509.Certificate, host string) {
+	pool := x509.NewCertPool()
+	for _, intermediate := range intermediates {
+		pool.AddCert(intermediate)
+	}
+	_, err := certificate.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         collector.roots,
+		Intermediates: pool,
+		CurrentTime:   collector.collectTime,
+	})
+	status.ChainChecked = true
+	status.ChainValid = err == nil
+	if err != nil {
+		status.ChainError = err.Error()
+	}
+}
+
+func publicKeySize(certificate *x509.Certificate) int {
+	switch key := certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	default:
+		return 0
+	}
+}
+
+func (collector *CertificateCollector) daysUntilExpiry(status certificateStatus) float64 {
+	return status.NotAfter.Sub(collector.collectTime).Hours() / 24
+}
+
+func (collector *CertificateCollector) expiryLevel(status certificateStatus) string {
+	days := collector.daysUntilExpiry(status)
+	switch {
+	case days <= 0:
+		return CertificateExpired
+	case days <= collector.config.CriticalDays:
+		return CertificateCritical
+	case days <= collector.config.WarningDays:
+		return CertificateWarning
+	default:
+		return CertificateOK
+	}
+}
+
+// detectChanges reports a certificate when it is first seen or replaced, with
+// its issuer and expiry, and whenever it crosses into a worse expiry level.
+// What was reported is kept in the state file across restarts.
+func (collector *CertificateCollector) detectChanges(name string, status certificateStatus) {
+	if _, known := collector.fingerprints[name]; !known {
+		collector.loadState(name)
+	}
+	savedFingerprint, savedLevel := collector.fingerprints[name], collector.levels[name]
+	defer func() {
+		if collector.fingerprints[name] != savedFingerprint || collector.levels[name] != savedLevel {
+			collector.saveState(name)
+		}
+	}()
+
+	data := map[string]interface{}{
+		"certificate": name,
+		"subject":     status.Subject,
+		"issuer":      status.Issuer,
+		"serial":      status.Serial,
+		"notAfter":    status.NotAfter.UTC().Format(time.RFC3339),
+		"keySize":     status.KeySize,
+	}
+	if status.ChainChecked {
+		data["chainValid"] = status.ChainValid
+		data["chainError"] = status.ChainError
+	}
+	if collector.fingerprints[name] != status.Fingerprint {
+		collector.fingerprints[name] = status.Fingerprint
+		delete(collector.levels, name)
+		collector.events = append(collector.events, Event{
+			Timestamp: collector.collectTime,
+			Device:    name,
+			Type:      EventCertificate,
+			Message:   fmt.Sprintf("Certificate of %s issued by %s expires %s", name, status.Issuer, status.NotAfter.UTC().Format(time.DateOnly)),
+			Data:      data,
+		})
+	}
+
+	level := collector.expiryLevel(status)
+	previous, seen := collector.levels[name]
+	collector.levels[name] = level
+	if level == CertificateOK || (seen && certificateLevelRank(level) <= certificateLevelRank(previous)) {
+		return
+	}
+	data["level"] = level
+	collector.events = append(collector.events, Event{
+		Timestamp: collector.collectTime,
+		Device:    name,
+		Type:      EventCertificateExpiry,
+		Message:   fmt.Sprintf("Certificate of %s is %s: %.1f days until expiry", name, level, collector.daysUntilExpiry(status)),
+		Data:      data,
+	})
+}
+
+func (collector *CertificateCollector) loadState(name string) {
+	if collector.stateService == nil {
+		return
+	}
+	value, ok := collector.stateService.GetValue(stateCertificatePrefix + name)
+	if !ok {
+		return
+	}
+	var state certificateState
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		slog.Error("Invalid saved certificate state", "certificate", name, "error", err)
+		return
+	}
+	collector.fingerprints[name] = state.Fingerprint
+	if state.Level != "" {
+		collector.levels[name] = state.Level
+	}
+}
+
+func (collector *CertificateCollector) saveState(name string) {
+	if collector.stateService == nil {
+		return
+	}
+	value, err := json.Marshal(certificateState{Fingerprint: collector.fingerprints[name], Level: collector.levels[name]})
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	collector.stateService.SetValue(stateCertificatePrefix+name, string(value))
+}
+
+func certificateLevelRank(level string) int {
+	switch level {
+	case CertificateWarning:
+		return 1
+	case CertificateCritical:
+		return 2
+	case CertificateExpired:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// GetEvents returns the events detected since the last call and clears them.
+func (collector *CertificateCollector) GetEvents() []Event {
+	events := collector.events
+	collector.events = nil
+	return events
+}
+
+// GetCertificateMetrics reports one instance per endpoint and per certificate
+// of a file. Chain validity is only reported for verified certificates.
+func (collector *CertificateCollector) GetCertificateMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for name, status := range collector.certificates {
+		metrics := map[string]float64{
+			"Certificate Days Until Expiry": collector.daysUntilExpiry(status),
+			"Certificate Key Size":          float64(status.KeySize),
+		}
+		if status.ChainChecked {
+			metrics["Certificate Chain Valid"] = boolMetric(status.ChainValid)
+		}
+		result[name] = metrics
+	}
+	return &result
+}
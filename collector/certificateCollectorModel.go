@@ -0,0 +1,47 @@
+package collector
+
+import "time"
+
+const CertificateSectionName = "certificate"
+
+// Certificate expiry levels, in increasing severity.
+const (
+	CertificateOK       = "ok"
+	CertificateWarning  = "warning"
+	CertificateCritical = "critical"
+	CertificateExpired  = "expired"
+)
+
+type CertificateConfig struct {
+	// Endpoints are host:port TLS endpoints, Paths are PEM or DER files and
+	// directories searched for them.
+	Endpoints []string
+	Paths     []string
+	// RootsFile adds private roots to the system roots for chain validation.
+	RootsFile    string
+	Timeout      time.Duration
+	WarningDays  float64
+	CriticalDays float64
+}
+
+// certificateStatus is the leaf certificate of an endpoint or one certificate
+// of a file. ChainChecked is false for certificates whose chain was not
+// verified, such as the intermediates following a leaf in a file.
+type certificateStatus struct {
+	Subject      string
+	Issuer       string
+	Serial       string
+	NotAfter     time.Time
+	KeySize      int
+	ChainChecked bool
+	ChainValid   bool
+	ChainError   string
+	Fingerprint  string
+}
+
+// certificateState is what the state file remembers of a certificate, so an
+// agent restart does not report known certificates and levels again.
+type certificateState struct {
+	Fingerprint string `json:"fingerprint"`
+	Level       string `json:"level,omitempty"`
+}
@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"if-win-dex-agent/cache"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// newTestCertificate issues a certificate expiring in the given number of
+// days, self-signed when parent is nil.
+func newTestCertificate(t *testing.T, name string, days int, isCA bool, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Duration(days) * 24 * time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	issuer, signer := template, key
+	if parent != nil {
+		issuer, signer = parent.certificate, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{certificate: certificate, key: key}
+}
+
+func writeCertificates(t *testing.T, path string, certificates ...*testCertificate) {
+	var data []byte
+	for _, certificate := range certificates {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.certificate.Raw})...)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertificateCollectorFiles(t *testing.T) {
+	directory := t.TempDir()
+	root := newTestCertificate(t, "Test Root", 3650, true, nil)
+	intermediate := newTestCertificate(t, "Test Intermediate", 1000, true, root)
+	leaf := newTestCertificate(t, "portal.example.test", 20, false, intermediate)
+	otherRoot := newTestCertificate(t, "Other Root", 5, true, nil)
+
+	rootsFile := filepath.Join(directory, "roots.txt")
+	writeCertificates(t, rootsFile, root)
+	chainFile := filepath.Join(directory, "chain.pem")
+	writeCertificates(t, chainFile, leaf, intermediate)
+	bundleFile := filepath.Join(directory, "bundle.pem")
+	writeCertificates(t, bundleFile, root, otherRoot)
+
+	config := CertificateConfig{Paths: []string{directory}, RootsFile: rootsFile, WarningDays: 30, CriticalDays: 7}
+	collector := NewCertificateCollector(config, nil)
+	collector.Collect()
+	metrics := *collector.GetCertificateMetrics()
+
+	tests := []struct {
+		instance       string
+		wantChainValid float64
+		wantChecked    bool
+		wantDays       float64
+	}{
+		{instance: chainFile + "#0", wantChainValid: 1, wantChecked: true, wantDays: 20},
+		{instance: chainFile + "#1", wantChecked: false, wantDays: 1000},
+		{instance: bundleFile + "#0", wantChainValid: 1, wantChecked: true, wantDays: 3650},
+		{instance: bundleFile + "#1", wantChainValid: 0, wantChecked: true, wantDays: 5},
+	}
+	if len(metrics) != len(tests) {
+		t.Errorf("got %d instances, want %d: %v", len(metrics), len(tests), metrics)
+	}
+	for _, test := range tests {
+		instance, ok := metrics[test.instance]
+		if !ok {
+			t.Errorf("missing instance %s", test.instance)
+			continue
+		}
+		if days := instance["Certificate Days Until Expiry"]; days < test.wantDays-1 || days > test.wantDays {
+			t.Errorf("%s: days until expiry = %.2f, want about %.0f", test.instance, days, test.wantDays)
+		}
+		chainValid, checked := instance["Certificate Chain Valid"]
+		if checked != test.wantChecked || chainValid != test.wantChainValid {
+			t.Errorf("%s: chain valid = %v (reported %v), want %v (reported %v)", test.instance, chainValid, checked, test.wantChainValid, test.wantChecked)
+		}
+	}
+
+	// First sighting of every certificate, the leaf crossing warning_days and
+	// the other root crossing critical_days.
+	events := collector.GetEvents()
+	counts := make(map[string]int)
+	for _, event := range events {
+		counts[event.Type]++
+	}
+	if counts[EventCertificate] != 4 || counts[EventCertificateExpiry] != 2 {
+		t.Errorf("events = %v, want 4 %s and 2 %s", counts, EventCertificate, EventCertificateExpiry)
+	}
+}
+
+func TestCertificateCollectorRemembersReportedCertificates(t *testing.T) {
+	directory := t.TempDir()
+	stateService, err := cache.CreateStateService(filepath.Join(directory, "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificateFile := filepath.Join(directory, "server.pem")
+	writeCertificates(t, certificateFile, newTestCertificate(t, "server.example.test", 3, false, nil))
+	config := CertificateConfig{Paths: []string{certificateFile}, WarningDays: 30, CriticalDays: 7}
+
+	first := NewCertificateCollector(config, stateService)
+	first.Collect()
+	if events := first.GetEvents(); len(events) != 2 {
+		t.Fatalf("first run raised %d events, want 2", len(events))
+	}
+
+	restarted := NewCertificateCollector(config, stateService)
+	restarted.Collect()
+	if events := restarted.GetEvents(); len(events) != 0 {
+		t.Errorf("restart raised %d events, want none", len(events))
+	}
+
+	writeCertificates(t, certificateFile, newTestCertificate(t, "server.example.test", 365, false, nil))
+	restarted.Collect()
+	events := restarted.GetEvents()
+	if len(events) != 1 || events[0].Type != EventCertificate {
+		t.Errorf("replacement raised %v, want one %s event", events, EventCertificate)
+	}
+}
@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"log/slog"
+	"time"
+)
+
+type ConnectionCollector struct {
+	source connectionSource
+	states map[string]map[string]int
+	// The counters of the last two scans give the failure, reset and
+	// retransmit rates.
+	counters        *tcpCounters
+	prevCounters    *tcpCounters
+	collectTime     time.Time
+	prevCollectTime time.Time
+}
+
+func NewConnectionCollector() *ConnectionCollector {
+	return &ConnectionCollector{source: newConnectionSource()}
+}
+
+func (collector *ConnectionCollector) Collect() {
+	states, err := collector.source.ConnectionStates()
+	if err != nil {
+		slog.Error("Error listing TCP connections", "error", err)
+	}
+	collector.states = states
+
+	collector.prevCounters = collector.counters
+	collector.prevCollectTime = collector.collectTime
+	collector.collectTime = time.Now()
+	counters, err := collector.source.TCPCounters()
+	if err != nil {
+		slog.Error("Error reading TCP counters", "error", err)
+		collector.counters = nil
+		return
+	}
+	collector.counters = &counters
+}
+
+func (collector *ConnectionCollector) GetConnectionMetrics() *map[string]map[string]float64 {
+	result := summarizeConnectionStates(collector.states)
+	if collector.counters != nil && collector.prevCounters != nil {
+		result[""] = tcpCounterMetrics(*collector.counters, *collector.prevCounters,
+			collector.collectTime.Sub(collector.prevCollectTime).Seconds())
+	}
+	return &result
+}
+
+// summarizeConnectionStates maps the connection counts of every address
+// family to the per-family metrics. Both families are always reported so a
+// family without connections shows up as zero instead of missing data.
+func summarizeConnectionStates(states map[string]map[string]int) map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for _, family := range []string{AddressFamilyIPv4, AddressFamilyIPv6} {
+		counts := states[family]
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+		result[family] = map[string]float64{
+			"TCP Connections": float64(total),
+			"TCP Established": float64(counts[TCPStateEstablished]),
+			"TCP Time Wait":   float64(counts[TCPStateTimeWait]),
+			"TCP Close Wait":  float64(counts[TCPStateCloseWait]),
+			"TCP Listen":      float64(counts[TCPStateListen]),
+		}
+	}
+	return result
+}
+
+// tcpCounterMetrics turns two samples of the TCP counters into rates.
+func tcpCounterMetrics(current tcpCounters, previous tcpCounters, seconds float64) map[string]float64 {
+	sentPerSec := counterRate(current.SegmentsSent, previous.SegmentsSent, seconds)
+	retransmittedPerSec := counterRate(current.SegmentsRetransmitted, previous.SegmentsRetransmitted, seconds)
+	retransmitPercent := 0.0
+	if sentPerSec > 0 {
+		retransmitPercent = retransmittedPerSec / sentPerSec * 100
+	}
+	return map[string]float64{
+		"TCP Connection Failures/s":    counterRate(current.ConnectionFailures, previous.ConnectionFailures, seconds),
+		"TCP Connection Resets/s":      counterRate(current.ConnectionResets, previous.ConnectionResets, seconds),
+		"TCP Segments Retransmitted/s": retransmittedPerSec,
+		"TCP Retransmit %":             retransmitPercent,
+	}
+}
@@ -0,0 +1,33 @@
+package collector
+
+// Address families the TCP connections are counted for.
+const (
+	AddressFamilyIPv4 = "TCPv4"
+	AddressFamilyIPv6 = "TCPv6"
+)
+
+// Normalized TCP states shared by every platform.
+const (
+	TCPStateEstablished = "ESTABLISHED"
+	TCPStateTimeWait    = "TIME_WAIT"
+	TCPStateCloseWait   = "CLOSE_WAIT"
+	TCPStateListen      = "LISTEN"
+)
+
+// tcpCounters are cumulative TCP counters since boot, summed over both
+// address families.
+type tcpCounters struct {
+	ConnectionFailures    uint64
+	ConnectionResets      uint64
+	SegmentsSent          uint64
+	SegmentsRetransmitted uint64
+}
+
+// connectionSource reads the TCP connection table and counters of the
+// machine, so the state-to-metric mapping works with any source.
+type connectionSource interface {
+	// ConnectionStates counts the TCP connections per address family and
+	// normalized state.
+	ConnectionStates() (map[string]map[string]int, error)
+	TCPCounters() (tcpCounters, error)
+}
@@ -0,0 +1,53 @@
+//go:build !windows
+
+package collector
+
+import (
+	"syscall"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// netConnectionSource reads the connection table and the TCP counters through
+// gopsutil, which uses /proc on Linux and the native tools elsewhere.
+type netConnectionSource struct{}
+
+func newConnectionSource() connectionSource {
+	return &netConnectionSource{}
+}
+
+func (source *netConnectionSource) ConnectionStates() (map[string]map[string]int, error) {
+	connections, err := net.Connections("tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]int)
+	for _, connection := range connections {
+		family := AddressFamilyIPv4
+		if connection.Family == syscall.AF_INET6 {
+			family = AddressFamilyIPv6
+		}
+		if result[family] == nil {
+			result[family] = make(map[string]int)
+		}
+		result[family][connection.Status]++
+	}
+	return result, nil
+}
+
+func (source *netConnectionSource) TCPCounters() (tcpCounters, error) {
+	stats, err := net.ProtoCounters([]string{"tcp"})
+	if err != nil {
+		return tcpCounters{}, err
+	}
+
+	var counters tcpCounters
+	for _, stat := range stats {
+		counters.ConnectionFailures += uint64(max(stat.Stats["AttemptFails"], 0))
+		counters.ConnectionResets += uint64(max(stat.Stats["EstabResets"], 0))
+		counters.SegmentsSent += uint64(max(stat.Stats["OutSegs"], 0))
+		counters.SegmentsRetransmitted += uint64(max(stat.Stats["RetransSegs"], 0))
+	}
+	return counters, nil
+}
@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeConnectionSource replays fixed connection counts and counters.
+type fakeConnectionSource struct {
+	states   map[string]map[string]int
+	counters []tcpCounters
+	err      error
+}
+
+func (source *fakeConnectionSource) ConnectionStates() (map[string]map[string]int, error) {
+	return source.states, source.err
+}
+
+func (source *fakeConnectionSource) TCPCounters() (tcpCounters, error) {
+	if source.err != nil || len(source.counters) == 0 {
+		return tcpCounters{}, errors.New("no counters")
+	}
+	counters := source.counters[0]
+	source.counters = source.counters[1:]
+	return counters, nil
+}
+
+func TestSummarizeConnectionStates(t *testing.T) {
+	tests := []struct {
+		name   string
+		states map[string]map[string]int
+		want   map[string]map[string]float64
+	}{
+		{
+			name:   "no connections reports both families as zero",
+			states: nil,
+			want: map[string]map[string]float64{
+				AddressFamilyIPv4: {"TCP Connections": 0, "TCP Established": 0, "TCP Time Wait": 0, "TCP Close Wait": 0, "TCP Listen": 0},
+				AddressFamilyIPv6: {"TCP Connections": 0, "TCP Established": 0, "TCP Time Wait": 0, "TCP Close Wait": 0, "TCP Listen": 0},
+			},
+		},
+		{
+			name: "states per family, other states only in the total",
+			states: map[string]map[string]int{
+				AddressFamilyIPv4: {TCPStateEstablished: 12, TCPStateTimeWait: 4, TCPStateCloseWait: 1, TCPStateListen: 7, "SYN_SENT": 2},
+				AddressFamilyIPv6: {TCPStateListen: 3, TCPStateEstablished: 1},
+			},
+			want: map[string]map[string]float64{
+				AddressFamilyIPv4: {"TCP Connections": 26, "TCP Established": 12, "TCP Time Wait": 4, "TCP Close Wait": 1, "TCP Listen": 7},
+				AddressFamilyIPv6: {"TCP Connections": 4, "TCP Established": 1, "TCP Time Wait": 0, "TCP Close Wait": 0, "TCP Listen": 3},
+			},
+		},
+		{
+			name: "unknown families are ignored",
+			states: map[string]map[string]int{
+				"UDPv4": {TCPStateEstablished: 5},
+			},
+			want: map[string]map[string]float64{
+				AddressFamilyIPv4: {"TCP Connections": 0, "TCP Established": 0, "TCP Time Wait": 0, "TCP Close Wait": 0, "TCP Listen": 0},
+				AddressFamilyIPv6: {"TCP Connections": 0, "TCP Established": 0, "TCP Time Wait": 0, "TCP Close Wait": 0, "TCP Listen": 0},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := summarizeConnectionStates(test.states)
+			if len(got) != len(test.want) {
+				t.Fatalf("got families %v, want %v", got, test.want)
+			}
+			for family, metrics := range test.want {
+				for metric, want := range metrics {
+					if got[family][metric] != want {
+						t.Errorf("%s %s = %v, want %v", family, metric, got[family][metric], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTCPCounterMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  tcpCounters
+		previous tcpCounters
+		seconds  float64
+		want     map[string]float64
+	}{
+		{
+			name:     "rates over the interval",
+			current:  tcpCounters{ConnectionFailures: 30, ConnectionResets: 60, SegmentsSent: 11000, SegmentsRetransmitted: 150},
+			previous: tcpCounters{ConnectionFailures: 0, ConnectionResets: 0, SegmentsSent: 1000, SegmentsRetransmitted: 50},
+			seconds:  10,
+			want: map[string]float64{
+				"TCP Connection Failures/s":    3,
+				"TCP Connection Resets/s":      6,
+				"TCP Segments Retransmitted/s": 10,
+				"TCP Retransmit %":             1,
+			},
+		},
+		{
+			name:     "nothing sent",
+			current:  tcpCounters{SegmentsSent: 500},
+			previous: tcpCounters{SegmentsSent: 500},
+			seconds:  10,
+			want: map[string]float64{
+				"TCP Connection Failures/s":    0,
+				"TCP Connection Resets/s":      0,
+				"TCP Segments Retransmitted/s": 0,
+				"TCP Retransmit %":             0,
+			},
+		},
+		{
+			name:     "counter reset",
+			current:  tcpCounters{ConnectionFailures: 1, SegmentsSent: 10},
+			previous: tcpCounters{ConnectionFailures: 100, SegmentsSent: 1000},
+			seconds:  10,
+			want: map[string]float64{
+				"TCP Connection Failures/s":    0,
+				"TCP Connection Resets/s":      0,
+				"TCP Segments Retransmitted/s": 0,
+				"TCP Retransmit %":             0,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := tcpCounterMetrics(test.current, test.previous, test.seconds)
+			for metric, want := range test.want {
+				if got[metric] != want {
+					t.Errorf("%s = %v, want %v", metric, got[metric], want)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectionCollectorReportsRatesFromTheSecondScan(t *testing.T) {
+	source := &fakeConnectionSource{
+		states: map[string]map[string]int{
+			AddressFamilyIPv4: {TCPStateEstablished: 2},
+		},
+		counters: []tcpCounters{{SegmentsSent: 100}, {SegmentsSent: 200}},
+	}
+	collector := &ConnectionCollector{source: source}
+
+	collector.Collect()
+	metrics := *collector.GetConnectionMetrics()
+	if _, ok := metrics[""]; ok {
+		t.Errorf("rates reported after a single scan: %v", metrics[""])
+	}
+	if metrics[AddressFamilyIPv4]["TCP Established"] != 2 {
+		t.Errorf("TCP Established = %v, want 2", metrics[AddressFamilyIPv4]["TCP Established"])
+	}
+
+	collector.Collect()
+	metrics = *collector.GetConnectionMetrics()
+	if _, ok := metrics[""]["TCP Retransmit %"]; !ok {
+		t.Errorf("no rates after the second scan: %v", metrics)
+	}
+}
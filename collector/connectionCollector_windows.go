@@ -0,0 +1,77 @@
+//go:build windows
+
+package collector
+
+import (
+	"errors"
+	"if-win-dex-agent/internal/headers/iphlpapi"
+	"if-win-dex-agent/internal/pdh"
+	"log/slog"
+
+	"golang.org/x/sys/windows"
+)
+
+// iphlpapiConnectionSource counts connections from the extended TCP tables
+// and reads the TCP counters from the TCPv4 and TCPv6 performance objects.
+type iphlpapiConnectionSource struct {
+	tcpCollectors []*pdh.Collector
+}
+
+func newConnectionSource() connectionSource {
+	source := &iphlpapiConnectionSource{}
+	for _, object := range []string{"TCPv4", "TCPv6"} {
+		tcpCollector, err := pdh.NewCollector[tcpData](object, nil)
+		if err != nil {
+			slog.Error("Error creating TCP counter collector", "object", object, "error", err)
+			continue
+		}
+		source.tcpCollectors = append(source.tcpCollectors, tcpCollector)
+	}
+	return source
+}
+
+func (source *iphlpapiConnectionSource) ConnectionStates() (map[string]map[string]int, error) {
+	result := make(map[string]map[string]int)
+	var errs []error
+	for family, addressFamily := range map[string]uint32{AddressFamilyIPv4: windows.AF_INET, AddressFamilyIPv6: windows.AF_INET6} {
+		states, err := iphlpapi.GetTCPConnectionStates(addressFamily)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result[family] = make(map[string]int)
+		for state, count := range states {
+			result[family][normalizeTCPState(state)] += int(count)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+func normalizeTCPState(state iphlpapi.MIB_TCP_STATE) string {
+	if state == iphlpapi.TCPStateListening {
+		return TCPStateListen
+	}
+	return state.String()
+}
+
+func (source *iphlpapiConnectionSource) TCPCounters() (tcpCounters, error) {
+	if len(source.tcpCollectors) == 0 {
+		return tcpCounters{}, errors.New("no TCP performance counters available")
+	}
+	var counters tcpCounters
+	for _, tcpCollector := range source.tcpCollectors {
+		var data []tcpData
+		if err := tcpCollector.Collect(&data); err != nil {
+			return tcpCounters{}, err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		// The per-second counters hold the raw running totals.
+		counters.ConnectionFailures += uint64(data[0].ConnectionFailures)
+		counters.ConnectionResets += uint64(data[0].ConnectionsReset)
+		counters.SegmentsSent += uint64(data[0].SegmentsSentPerSec)
+		counters.SegmentsRetransmitted += uint64(data[0].SegmentsRetransmittedPerSec)
+	}
+	return counters, nil
+}
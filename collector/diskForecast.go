@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+)
+
+// updateForecasts records the used bytes of every volume in the cache and fits
+// a linear trend over the forecast window to estimate when it will be full.
+func (collector *FilesystemCollector) updateForecasts(now time.Time) {
+	collector.forecasts = make(map[string]diskForecast)
+	if collector.cacheService == nil {
+		return
+	}
+	since := now.Add(-collector.config.ForecastWindow).UnixMilli()
+	collector.cacheService.PruneVolumeUsage(since)
+
+	for _, volume := range collector.volumes {
+		usedBytes := float64(volume.Usage.TotalBytes - volume.Usage.FreeBytes)
+		totalBytes := float64(volume.Usage.TotalBytes)
+		collector.cacheService.AddVolumeUsage(volume.Mountpoint, now.UnixMilli(), usedBytes, totalBytes)
+
+		history := *collector.cacheService.GetVolumeUsageHistory(volume.Mountpoint, since)
+		if len(history) < max(collector.config.ForecastMinSamples, 2) {
+			continue
+		}
+		hours := make([]float64, len(history))
+		used := make([]float64, len(history))
+		for i, sample := range history {
+			hours[i] = float64(sample.Timestamp-history[0].Timestamp) / float64(time.Hour.Milliseconds())
+			used[i] = sample.UsedBytes
+		}
+		slope, ok := linearSlope(hours, used)
+		if !ok {
+			continue
+		}
+
+		forecast := diskForecast{GrowthBytesPerHour: slope}
+		// A flat or shrinking volume never fills up, so no forecast is made.
+		if slope > 0 {
+			forecast.HoursUntilFull = min((totalBytes-usedBytes)/slope, collector.config.ForecastMaxHours)
+			forecast.HasHoursUntilFull = true
+		}
+		collector.forecasts[volume.Mountpoint] = forecast
+		collector.checkForecastThreshold(volume.Mountpoint, forecast, now)
+	}
+}
+
+// checkForecastThreshold raises an event when a volume first drops below the
+// configured time-to-full, and re-arms once it recovers.
+func (collector *FilesystemCollector) checkForecastThreshold(mountpoint string, forecast diskForecast, now time.Time) {
+	if collector.config.ForecastEventHours <= 0 {
+		return
+	}
+	below := forecast.HasHoursUntilFull && forecast.HoursUntilFull < collector.config.ForecastEventHours
+	if below && !collector.forecastAlerts[mountpoint] {
+		collector.events = append(collector.events, Event{
+			Timestamp: now,
+			Device:    mountpoint,
+			Type:      EventDiskFullForecast,
+			Message:   fmt.Sprintf("Volume %s is forecast to be full in %.1f hours", mountpoint, forecast.HoursUntilFull),
+			Data: map[string]interface{}{
+				"volume":             mountpoint,
+				"hoursUntilFull":     forecast.HoursUntilFull,
+				"growthBytesPerHour": forecast.GrowthBytesPerHour,
+				"thresholdHours":     collector.config.ForecastEventHours,
+			},
+		})
+	}
+	collector.forecastAlerts[mountpoint] = below
+}
+
+// linearSlope returns the least squares slope of y over x. It fails when all x
+// values are equal.
+func linearSlope(x []float64, y []float64) (float64, bool) {
+	n := float64(len(x))
+	if n < 2 || len(x) != len(y) {
+		return 0, false
+	}
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var covariance, variance float64
+	for i := range x {
+		covariance += (x[i] - meanX) * (y[i] - meanY)
+		variance += (x[i] - meanX) * (x[i] - meanX)
+	}
+	if variance == 0 {
+		return 0, false
+	}
+	return covariance / variance, true
+}
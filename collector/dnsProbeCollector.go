@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+type DNSProbeCollector struct {
+	config  DNSProbeConfig
+	results map[string]dnsProbeResult
+}
+
+func NewDNSProbeCollector(config DNSProbeConfig) *DNSProbeCollector {
+	return &DNSProbeCollector{config: config, results: make(map[string]dnsProbeResult)}
+}
+
+// LoadDNSProbeConfig reads the probe targets from [dns_probe:<name>] sections,
+// for example:
+//
+//	[dns_probe:Intranet]
+//	query = intranet.example.com
+//	servers = 10.0.0.53, 10.0.1.53:53
+//	timeout = 5s
+//	attempts = 3
+func LoadDNSProbeConfig(p *configparser.ConfigParser) DNSProbeConfig {
+	config := DNSProbeConfig{}
+	for _, section := range getSectionsWithPrefix(p, DNSProbeSectionPrefix) {
+		target := DNSProbeTarget{
+			Name:     strings.TrimSpace(strings.TrimPrefix(section, DNSProbeSectionPrefix)),
+			Query:    getConfigString(p, section, "query", ""),
+			Timeout:  getConfigDuration(p, section, "timeout", 5*time.Second),
+			Attempts: max(getConfigInt(p, section, "attempts", 1), 1),
+		}
+		if target.Query == "" {
+			slog.Error("DNS probe has no query, skipping it", "probe", target.Name)
+			continue
+		}
+		for _, server := range getConfigList(p, section, "servers") {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(server, "53")
+			}
+			target.Servers = append(target.Servers, server)
+		}
+		config.Targets = append(config.Targets, target)
+	}
+	return config
+}
+
+// dnsProbeDevice names the instance of a target and resolver. The system
+// resolver uses the plain target name.
+func dnsProbeDevice(target DNSProbeTarget, server string) string {
+	if server == "" {
+		return target.Name
+	}
+	return target.Name + " @" + server
+}
+
+// Collect resolves every target against every resolver concurrently.
+func (collector *DNSProbeCollector) Collect() {
+	results := make(map[string]dnsProbeResult)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, target := range collector.config.Targets {
+		for _, server := range append([]string{""}, target.Servers...) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result := probeDNS(target, server)
+				mutex.Lock()
+				results[dnsProbeDevice(target, server)] = result
+				mutex.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+	collector.results = results
+}
+
+// probeDNS resolves the target Attempts times. An empty server uses the system
+// resolver; otherwise the pure Go resolver queries the server directly.
+func probeDNS(target DNSProbeTarget, server string) dnsProbeResult {
+	resolver := net.DefaultResolver
+	if server != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	result := dnsProbeResult{Attempts: target.Attempts}
+	for range target.Attempts {
+		ctx, cancel := context.WithTimeout(context.Background(), target.Timeout)
+		start := time.Now()
+		addresses, err := resolver.LookupHost(ctx, target.Query)
+		result.TotalTime += time.Since(start)
+		cancel()
+
+		if err == nil {
+			result.Successes++
+			result.AnswerCount = len(addresses)
+			continue
+		}
+		switch classifyDNSError(err) {
+		case dnsRcodeNXDomain:
+			result.NXDomainCount++
+		case dnsRcodeServFail:
+			result.ServFailCount++
+		}
+		slog.Warn("DNS probe failed", "probe", target.Name, "query", target.Query, "server", server, "error", err)
+	}
+	return result
+}
+
+const (
+	dnsRcodeOther = iota
+	dnsRcodeNXDomain
+	dnsRcodeServFail
+)
+
+// classifyDNSError maps a lookup error to the answer that most likely caused
+// it. Go does not expose the response code, so a missing name is NXDOMAIN and
+// any other temporary failure that is not a timeout is counted as SERVFAIL.
+// The pure Go resolver reports exactly these for the two codes; the Windows
+// system resolver only distinguishes a missing host from a retryable failure,
+// so its SERVFAIL count also includes refused or unreachable servers.
+func classifyDNSError(err error) int {
+	var dnsError *net.DNSError
+	if !errors.As(err, &dnsError) {
+		return dnsRcodeOther
+	}
+	switch {
+	case dnsError.IsNotFound:
+		return dnsRcodeNXDomain
+	case dnsError.IsTemporary && !dnsError.IsTimeout:
+		return dnsRcodeServFail
+	}
+	return dnsRcodeOther
+}
+
+// GetProbeMetrics reports one instance per target and resolver. Success is
+// the share of successful attempts and the latency is averaged over them all.
+func (collector *DNSProbeCollector) GetProbeMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for device, probe := range collector.results {
+		result[device] = map[string]float64{
+			"DNS Resolution ms":  float64(probe.TotalTime.Microseconds()) / 1000 / float64(probe.Attempts),
+			"DNS Success":        float64(probe.Successes) / float64(probe.Attempts),
+			"DNS Answer Count":   float64(probe.AnswerCount),
+			"DNS NXDOMAIN Count": float64(probe.NXDomainCount),
+			"DNS SERVFAIL Count": float64(probe.ServFailCount),
+		}
+	}
+	return &result
+}
@@ -0,0 +1,29 @@
+package collector
+
+import "time"
+
+const DNSProbeSectionPrefix = "dns_probe:"
+
+// DNSProbeTarget is a name resolved on every collection with the system
+// resolver and with each of Servers, Attempts times per resolver.
+type DNSProbeTarget struct {
+	Name     string
+	Query    string
+	Servers  []string
+	Timeout  time.Duration
+	Attempts int
+}
+
+type DNSProbeConfig struct {
+	Targets []DNSProbeTarget
+}
+
+// dnsProbeResult summarizes the attempts against one resolver.
+type dnsProbeResult struct {
+	Attempts      int
+	Successes     int
+	TotalTime     time.Duration
+	AnswerCount   int
+	NXDomainCount int
+	ServFailCount int
+}
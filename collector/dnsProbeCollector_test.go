@@ -0,0 +1,138 @@
+package collector
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startDNSStandIn answers queries on a local UDP port: names starting with
+// "missing" get NXDOMAIN, names starting with "fail" get SERVFAIL and any other
+// name resolves to two IPv4 addresses.
+func startDNSStandIn(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, address, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if response := dnsStandInResponse(buffer[:n]); response != nil {
+				conn.WriteTo(response, address)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func dnsStandInResponse(query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+	var labels []string
+	offset := 12
+	for offset < len(query) && query[offset] != 0 {
+		length := int(query[offset])
+		if offset+1+length > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[offset+1:offset+1+length]))
+		offset += 1 + length
+	}
+	if offset+5 > len(query) {
+		return nil
+	}
+	question := query[12 : offset+5]
+	questionType := binary.BigEndian.Uint16(query[offset+1:])
+	name := strings.ToLower(strings.Join(labels, "."))
+
+	rcode := uint16(0)
+	var answers [][]byte
+	switch {
+	case strings.HasPrefix(name, "missing"):
+		rcode = 3
+	case strings.HasPrefix(name, "fail"):
+		rcode = 2
+	case questionType == 1:
+		for _, last := range []byte{1, 2} {
+			// Name pointer to the question, type A, class IN, TTL 60, 4 bytes.
+			answers = append(answers, []byte{0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 10, 0, 0, last})
+		}
+	}
+
+	response := make([]byte, 12, 12+len(question)+len(answers)*16)
+	copy(response, query[:2])
+	binary.BigEndian.PutUint16(response[2:], 0x8180|rcode)
+	binary.BigEndian.PutUint16(response[4:], 1)
+	binary.BigEndian.PutUint16(response[6:], uint16(len(answers)))
+	response = append(response, question...)
+	for _, answer := range answers {
+		response = append(response, answer...)
+	}
+	return response
+}
+
+func TestProbeDNS(t *testing.T) {
+	server := startDNSStandIn(t)
+	tests := []struct {
+		name          string
+		query         string
+		wantSuccesses int
+		wantAnswers   int
+		wantNXDomain  int
+		wantServFail  int
+	}{
+		{name: "success", query: "intranet.example.test.", wantSuccesses: 2, wantAnswers: 2},
+		{name: "nxdomain", query: "missing.example.test.", wantNXDomain: 2},
+		{name: "servfail", query: "fail.example.test.", wantServFail: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := DNSProbeTarget{Name: test.name, Query: test.query, Timeout: 2 * time.Second, Attempts: 2}
+			result := probeDNS(target, server)
+			if result.Attempts != 2 {
+				t.Errorf("attempts = %d, want 2", result.Attempts)
+			}
+			if result.Successes != test.wantSuccesses {
+				t.Errorf("successes = %d, want %d", result.Successes, test.wantSuccesses)
+			}
+			if result.AnswerCount != test.wantAnswers {
+				t.Errorf("answer count = %d, want %d", result.AnswerCount, test.wantAnswers)
+			}
+			if result.NXDomainCount != test.wantNXDomain {
+				t.Errorf("NXDOMAIN count = %d, want %d", result.NXDomainCount, test.wantNXDomain)
+			}
+			if result.ServFailCount != test.wantServFail {
+				t.Errorf("SERVFAIL count = %d, want %d", result.ServFailCount, test.wantServFail)
+			}
+		})
+	}
+}
+
+func TestClassifyDNSError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "not found", err: &net.DNSError{Err: "no such host", IsNotFound: true}, want: dnsRcodeNXDomain},
+		{name: "server failure", err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}, want: dnsRcodeServFail},
+		{name: "timeout", err: &net.DNSError{Err: "i/o timeout", IsTimeout: true, IsTemporary: true}, want: dnsRcodeOther},
+		{name: "other", err: &net.OpError{Op: "dial"}, want: dnsRcodeOther},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := classifyDNSError(test.err); got != test.want {
+				t.Errorf("classifyDNSError() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
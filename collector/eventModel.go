@@ -0,0 +1,42 @@
+package collector
+
+import "time"
+
+const EventSectionName = "events"
+
+const (
+	EventProcessStart       = "ProcessStart"
+	EventProcessExit        = "ProcessExit"
+	EventProcessCrashLoop   = "ProcessCrashLoop"
+	EventUserTopApplication = "UserTopApplication"
+	EventDiskFullForecast   = "DiskFullForecast"
+	EventHostInventory      = "HostInventory"
+	EventReboot             = "Reboot"
+	EventListeningPorts     = "ListeningPorts"
+	EventPortConflict       = "PortConflict"
+	EventNetworkInterface   = "NetworkInterfaceChange"
+	EventTransactionFailed  = "TransactionFailed"
+	EventCertificate        = "Certificate"
+	EventCertificateExpiry  = "CertificateExpiry"
+)
+
+// Event is a point-in-time occurrence detected by a collector, as opposed to a
+// metric sampled every interval. Device follows the same convention as the
+// metric maps and is combined with the agent name to form the instance.
+type Event struct {
+	Timestamp time.Time
+	Device    string
+	Type      string
+	Message   string
+	Data      map[string]interface{}
+}
+
+// EventConfig selects where events are sent. Events whose type is listed in
+// ChangeTypes go to the deployment project and those listed in IncidentTypes
+// to the incident project; any other event is only logged.
+type EventConfig struct {
+	IncidentProject   string
+	DeploymentProject string
+	ChangeTypes       []string
+	IncidentTypes     []string
+}
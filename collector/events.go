@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"slices"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+// defaultChangeTypes are the events describing a change of the host rather
+// than a problem on it.
+var defaultChangeTypes = []string{
+	EventHostInventory,
+	EventListeningPorts,
+	EventNetworkInterface,
+	EventCertificate,
+}
+
+// defaultIncidentTypes are the events worth an incident. Process starts and
+// exits or top applications are routine and only logged.
+var defaultIncidentTypes = []string{
+	EventProcessCrashLoop,
+	EventReboot,
+	EventPortConflict,
+	EventTransactionFailed,
+	EventCertificateExpiry,
+	EventDiskFullForecast,
+}
+
+// LoadEventConfig reads the event destinations from [events], for example:
+//
+//	[events]
+//	incident_project = Win-Dex-Agent-Incident
+//	deployment_project = Win-Dex-Agent-Deployment
+//	change_types = HostInventory, ListeningPorts, NetworkInterfaceChange, Certificate
+//	incident_types = ProcessCrashLoop, Reboot, PortConflict, TransactionFailed, CertificateExpiry, DiskFullForecast
+func LoadEventConfig(p *configparser.ConfigParser) EventConfig {
+	config := EventConfig{
+		IncidentProject:   getConfigString(p, EventSectionName, "incident_project", "Win-Dex-Agent-Incident"),
+		DeploymentProject: getConfigString(p, EventSectionName, "deployment_project", "Win-Dex-Agent-Deployment"),
+		ChangeTypes:       getConfigList(p, EventSectionName, "change_types"),
+		IncidentTypes:     getConfigList(p, EventSectionName, "incident_types"),
+	}
+	if len(config.ChangeTypes) == 0 {
+		config.ChangeTypes = defaultChangeTypes
+	}
+	if len(config.IncidentTypes) == 0 {
+		config.IncidentTypes = defaultIncidentTypes
+	}
+	return config
+}
+
+// IsChangeEvent reports whether events of the type go to the deployment project.
+func (config EventConfig) IsChangeEvent(eventType string) bool {
+	return slices.Contains(config.ChangeTypes, eventType)
+}
+
+// IsIncidentEvent reports whether events of the type go to the incident project.
+func (config EventConfig) IsIncidentEvent(eventType string) bool {
+	return slices.Contains(config.IncidentTypes, eventType)
+}
@@ -0,0 +1,197 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+type ExecCollector struct {
+	config  ExecConfig
+	lastRun map[string]time.Time
+	results map[string]execResult
+}
+
+func NewExecCollector(config ExecConfig) *ExecCollector {
+	return &ExecCollector{
+		config:  config,
+		lastRun: make(map[string]time.Time),
+		results: make(map[string]execResult),
+	}
+}
+
+// LoadExecConfig reads the commands from [exec:<name>] sections, for example:
+//
+//	[exec:BackupAge]
+//	command = powershell -NoProfile -File C:\Scripts\backup_age.ps1
+//	format = auto
+//	device = Backup
+//	timeout = 30s
+//	interval = 15m
+//	max_output_bytes = 65536
+func LoadExecConfig(p *configparser.ConfigParser) ExecConfig {
+	config := ExecConfig{}
+	for _, section := range getSectionsWithPrefix(p, ExecSectionPrefix) {
+		command := ExecCommand{
+			Name:           strings.TrimSpace(strings.TrimPrefix(section, ExecSectionPrefix)),
+			Command:        getConfigString(p, section, "command", ""),
+			Format:         strings.ToLower(getConfigString(p, section, "format", ExecFormatAuto)),
+			Timeout:        getConfigDuration(p, section, "timeout", 30*time.Second),
+			Interval:       getConfigDuration(p, section, "interval", 0),
+			MaxOutputBytes: getConfigInt(p, section, "max_output_bytes", 64*1024),
+		}
+		command.Device = getConfigString(p, section, "device", command.Name)
+		if command.Command == "" {
+			slog.Error("Exec command has no command, skipping it", "exec", command.Name)
+			continue
+		}
+		switch command.Format {
+		case ExecFormatAuto, ExecFormatSimple, ExecFormatPrometheus, ExecFormatJSON:
+		default:
+			slog.Error("Unknown exec output format, skipping it", "exec", command.Name, "format", command.Format)
+			continue
+		}
+		config.Commands = append(config.Commands, command)
+	}
+	return config
+}
+
+// Collect runs the commands that are due concurrently, so a slow script does
+// not delay the others beyond its own timeout.
+func (collector *ExecCollector) Collect() {
+	results := make(map[string]execResult)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	now := time.Now()
+	for _, command := range collector.config.Commands {
+		if last, ok := collector.lastRun[command.Name]; ok && now.Sub(last) < command.Interval {
+			continue
+		}
+		collector.lastRun[command.Name] = now
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := runExecCommand(command)
+			mutex.Lock()
+			results[command.Name] = result
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+	collector.results = results
+}
+
+// runExecCommand runs the command with its timeout and parses its output. The
+// whole process group is killed on timeout and once the command exits, so
+// children left running by a script do not pile up.
+func runExecCommand(command ExecCommand) execResult {
+	result := execResult{ExitCode: -1}
+	ctx, cancel := context.WithTimeout(context.Background(), command.Timeout)
+	defer cancel()
+
+	group := &processGroup{}
+	cmd := shellCommand(ctx, command.Command)
+	stdout := &limitedBuffer{limit: command.MaxOutputBytes}
+	stderr := &limitedBuffer{limit: 1024}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Cancel = group.kill
+	// Stop waiting for output still held open by orphaned children.
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		slog.Warn("Failed to start exec command", "exec", command.Name, "error", err)
+		return result
+	}
+	if err := group.attach(cmd); err != nil {
+		slog.Warn("Failed to set up the process group of exec command", "exec", command.Name, "error", err)
+	}
+	err := cmd.Wait()
+	result.Duration = time.Since(start)
+	group.kill()
+	group.close()
+
+	result.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+	result.Truncated = stdout.truncated
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		slog.Warn("Exec command failed", "exec", command.Name, "exitCode", result.ExitCode,
+			"timedOut", result.TimedOut, "error", err, "stderr", strings.TrimSpace(stderr.String()))
+	}
+
+	output := stdout.String()
+	if result.Truncated {
+		// The last line is cut off and could hold a wrong value.
+		output = output[:strings.LastIndex(output, "\n")+1]
+		slog.Warn("Exec command output was truncated", "exec", command.Name, "limit", command.MaxOutputBytes)
+	}
+	metrics, parseErr := parseMetricOutput(output, command.Format, command.Device)
+	if parseErr != nil {
+		slog.Warn("Failed to parse exec command output", "exec", command.Name, "error", parseErr)
+	}
+	result.Metrics = metrics
+	return result
+}
+
+// GetExecMetrics reports the metrics printed by the commands that ran in the
+// last collection, next to the exit status of each under the command name.
+func (collector *ExecCollector) GetExecMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for name, execResult := range collector.results {
+		for device, metrics := range execResult.Metrics {
+			if _, ok := result[device]; !ok {
+				result[device] = make(map[string]float64)
+			}
+			for metric, value := range metrics {
+				result[device][metric] = value
+			}
+		}
+		metricCount := 0
+		for _, metrics := range execResult.Metrics {
+			metricCount += len(metrics)
+		}
+		if _, ok := result[name]; !ok {
+			result[name] = make(map[string]float64)
+		}
+		result[name]["Exec Exit Code"] = float64(execResult.ExitCode)
+		result[name]["Exec Success"] = boolMetric(execResult.ExitCode == 0 && !execResult.TimedOut)
+		result[name]["Exec Duration ms"] = float64(execResult.Duration.Milliseconds())
+		result[name]["Exec Timed Out"] = boolMetric(execResult.TimedOut)
+		result[name]["Exec Output Truncated"] = boolMetric(execResult.Truncated)
+		result[name]["Exec Metric Count"] = float64(metricCount)
+	}
+	return &result
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest, without failing the writes so the command is not broken off. It
+// wraps rather than embeds bytes.Buffer, whose ReadFrom would bypass Write.
+type limitedBuffer struct {
+	buffer    bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (buffer *limitedBuffer) Write(p []byte) (int, error) {
+	if room := buffer.limit - buffer.buffer.Len(); room < len(p) {
+		buffer.truncated = true
+		if room > 0 {
+			buffer.buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return buffer.buffer.Write(p)
+}
+
+func (buffer *limitedBuffer) String() string {
+	return buffer.buffer.String()
+}
@@ -0,0 +1,42 @@
+package collector
+
+import "time"
+
+const ExecSectionPrefix = "exec:"
+
+// Output formats of exec commands. In auto mode the format is detected from
+// the output.
+const (
+	ExecFormatAuto       = "auto"
+	ExecFormatSimple     = "simple"
+	ExecFormatPrometheus = "prometheus"
+	ExecFormatJSON       = "json"
+)
+
+// ExecCommand is a command or script run through the shell of the platform,
+// cmd.exe on Windows and /bin/sh elsewhere. Its standard output is parsed
+// into metrics; those without a device are reported under Device.
+type ExecCommand struct {
+	Name    string
+	Command string
+	Format  string
+	Device  string
+	Timeout time.Duration
+	// Interval between runs, or 0 to run on every collection.
+	Interval time.Duration
+	// MaxOutputBytes caps the standard output read; the rest is discarded.
+	MaxOutputBytes int
+}
+
+type ExecConfig struct {
+	Commands []ExecCommand
+}
+
+// execResult is the outcome of one run of a command.
+type execResult struct {
+	Metrics   map[string]map[string]float64
+	ExitCode  int
+	Duration  time.Duration
+	TimedOut  bool
+	Truncated bool
+}
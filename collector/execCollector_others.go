@@ -0,0 +1,41 @@
+//go:build !windows
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// processGroup is the process group the command leads, which its children
+// inherit unless they start their own.
+type processGroup struct {
+	pid int
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+func (group *processGroup) attach(cmd *exec.Cmd) error {
+	group.pid = cmd.Process.Pid
+	return nil
+}
+
+// kill kills every process left in the group.
+func (group *processGroup) kill() error {
+	if group.pid <= 0 {
+		return nil
+	}
+	err := syscall.Kill(-group.pid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+func (group *processGroup) close() {}
@@ -0,0 +1,127 @@
+//go:build windows
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processGroup is a job object the command is assigned to while it is still
+// suspended, so every child it creates belongs to the job as well.
+type processGroup struct {
+	job     windows.Handle
+	process *os.Process
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	shell := os.Getenv("ComSpec")
+	if shell == "" {
+		shell = "cmd.exe"
+	}
+	cmd := exec.CommandContext(ctx, shell)
+	// cmd.exe does not follow the quoting rules of exec, so the command line
+	// is passed as is.
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine:       `"` + shell + `" /S /C "` + command + `"`,
+		HideWindow:    true,
+		CreationFlags: windows.CREATE_SUSPENDED,
+	}
+	return cmd
+}
+
+// attach assigns the suspended command to a new job object and then resumes
+// it. A command that cannot be resumed is killed rather than left to hang
+// until its timeout.
+func (group *processGroup) attach(cmd *exec.Cmd) error {
+	group.process = cmd.Process
+	err := group.assign(uint32(cmd.Process.Pid))
+	if resumeErr := resumeProcess(uint32(cmd.Process.Pid)); resumeErr != nil {
+		group.kill()
+		return resumeErr
+	}
+	return err
+}
+
+func (group *processGroup) assign(pid uint32) error {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return err
+	}
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		windows.CloseHandle(job)
+		return err
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, pid)
+	if err != nil {
+		windows.CloseHandle(job)
+		return err
+	}
+	defer windows.CloseHandle(process)
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		windows.CloseHandle(job)
+		return err
+	}
+	group.job = job
+	return nil
+}
+
+// resumeProcess resumes the main thread of a process created suspended, which
+// is its only thread.
+func resumeProcess(pid uint32) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	entry := windows.ThreadEntry32{Size: uint32(unsafe.Sizeof(windows.ThreadEntry32{}))}
+	err = windows.Thread32First(snapshot, &entry)
+	for err == nil && entry.OwnerProcessID != pid {
+		err = windows.Thread32Next(snapshot, &entry)
+	}
+	if errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return errors.New("no thread found to resume")
+	}
+	if err != nil {
+		return err
+	}
+	thread, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(thread)
+	_, err = windows.ResumeThread(thread)
+	return err
+}
+
+// kill terminates every process in the job, or only the command when it could
+// not be assigned to one.
+func (group *processGroup) kill() error {
+	if group.job != 0 {
+		return windows.TerminateJobObject(group.job, 1)
+	}
+	if group.process != nil {
+		return group.process.Kill()
+	}
+	return nil
+}
+
+func (group *processGroup) close() {
+	if group.job != 0 {
+		windows.CloseHandle(group.job)
+		group.job = 0
+	}
+}
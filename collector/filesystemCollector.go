@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"if-win-dex-agent/cache"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+type FilesystemCollector struct {
+	config       FilesystemConfig
+	cacheService *cache.CacheService
+	volumes      []volumeUsage
+	forecasts    map[string]diskForecast
+	// Volumes currently below the forecast event threshold.
+	forecastAlerts map[string]bool
+	events         []Event
+}
+
+func NewFilesystemCollector(config FilesystemConfig, cacheService *cache.CacheService) *FilesystemCollector {
+	return &FilesystemCollector{
+		config:         config,
+		cacheService:   cacheService,
+		forecasts:      make(map[string]diskForecast),
+		forecastAlerts: make(map[string]bool),
+	}
+}
+
+// LoadFilesystemConfig reads the [filesystem] section. Removable, network and
+// pseudo filesystems are excluded unless turned back on.
+func LoadFilesystemConfig(p *configparser.ConfigParser) FilesystemConfig {
+	return FilesystemConfig{
+		ExcludeRemovable:   getConfigBool(p, FilesystemSectionName, "exclude_removable", true),
+		ExcludeNetwork:     getConfigBool(p, FilesystemSectionName, "exclude_network", true),
+		ExcludePseudo:      getConfigBool(p, FilesystemSectionName, "exclude_pseudo", true),
+		ExcludeMountpoints: getConfigList(p, FilesystemSectionName, "exclude_mountpoints"),
+		ExcludeFstypes:     getConfigList(p, FilesystemSectionName, "exclude_fstypes"),
+		ForecastWindow:     getConfigDuration(p, FilesystemSectionName, "forecast_window", 24*time.Hour),
+		ForecastMinSamples: getConfigInt(p, FilesystemSectionName, "forecast_min_samples", 3),
+		ForecastMaxHours:   getConfigFloat(p, FilesystemSectionName, "forecast_max_hours", 24*365),
+		ForecastEventHours: getConfigFloat(p, FilesystemSectionName, "forecast_event_hours", 0),
+	}
+}
+
+func (collector *FilesystemCollector) Collect() {
+	collector.volumes = nil
+	partitions, err := disk.Partitions(!collector.config.ExcludePseudo)
+	if err != nil {
+		slog.Error("Error fetching disk partitions", "error", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, partition := range partitions {
+		if seen[partition.Mountpoint] {
+			continue
+		}
+		class := classifyPartition(partition)
+		if collector.isExcluded(partition, class) {
+			continue
+		}
+		usage, err := disk.Usage(partition.Mountpoint)
+		if err != nil || usage.Total == 0 {
+			continue
+		}
+		seen[partition.Mountpoint] = true
+		collector.volumes = append(collector.volumes, volumeUsage{
+			Mountpoint: partition.Mountpoint,
+			Fstype:     partition.Fstype,
+			Class:      class,
+			Usage: DiskUsage{
+				FreeBytes:      usage.Total - usage.Used,
+				TotalBytes:     usage.Total,
+				AvailableBytes: usage.Free,
+			},
+			InodesTotal: usage.InodesTotal,
+			InodesUsed:  usage.InodesUsed,
+			InodesFree:  usage.InodesFree,
+		})
+	}
+	collector.updateForecasts(time.Now())
+}
+
+func (collector *FilesystemCollector) isExcluded(partition disk.PartitionStat, class string) bool {
+	switch {
+	case class == VolumeRemovable || class == VolumeOptical:
+		if collector.config.ExcludeRemovable {
+			return true
+		}
+	case class == VolumeNetwork:
+		if collector.config.ExcludeNetwork {
+			return true
+		}
+	case class == VolumePseudo:
+		if collector.config.ExcludePseudo {
+			return true
+		}
+	}
+	for _, mountpoint := range collector.config.ExcludeMountpoints {
+		if strings.EqualFold(strings.TrimRight(mountpoint, `/\`), strings.TrimRight(partition.Mountpoint, `/\`)) {
+			return true
+		}
+	}
+	for _, fstype := range collector.config.ExcludeFstypes {
+		if strings.EqualFold(fstype, partition.Fstype) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFilesystemMetrics reports capacity per volume, keyed by mount point.
+func (collector *FilesystemCollector) GetFilesystemMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for _, volume := range collector.volumes {
+		usedBytes := volume.Usage.TotalBytes - volume.Usage.FreeBytes
+		result[volume.Mountpoint] = map[string]float64{
+			"Disk Total Bytes":     float64(volume.Usage.TotalBytes),
+			"Disk Used Bytes":      float64(usedBytes),
+			"Disk Free Bytes":      float64(volume.Usage.FreeBytes),
+			"Disk Available Bytes": float64(volume.Usage.AvailableBytes),
+			"Disk Used %":          float64(usedBytes) / float64(volume.Usage.TotalBytes) * 100,
+		}
+		if volume.InodesTotal > 0 {
+			result[volume.Mountpoint]["Disk Inodes Used"] = float64(volume.InodesUsed)
+			result[volume.Mountpoint]["Disk Inodes Free"] = float64(volume.InodesFree)
+			result[volume.Mountpoint]["Disk Inodes Used %"] = float64(volume.InodesUsed) / float64(volume.InodesTotal) * 100
+		}
+		if forecast, ok := collector.forecasts[volume.Mountpoint]; ok {
+			result[volume.Mountpoint]["Disk Growth Bytes/h"] = forecast.GrowthBytesPerHour
+			if forecast.HasHoursUntilFull {
+				result[volume.Mountpoint]["Disk Hours Until Full"] = forecast.HoursUntilFull
+			}
+		}
+	}
+	return &result
+}
+
+// GetEvents returns the events detected since the last call and clears them.
+func (collector *FilesystemCollector) GetEvents() []Event {
+	events := collector.events
+	collector.events = nil
+	return events
+}
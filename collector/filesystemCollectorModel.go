@@ -0,0 +1,47 @@
+package collector
+
+import "time"
+
+const FilesystemSectionName = "filesystem"
+
+// Volume classes used to filter partitions.
+const (
+	VolumeFixed     = "fixed"
+	VolumeRemovable = "removable"
+	VolumeNetwork   = "network"
+	VolumePseudo    = "pseudo"
+	VolumeOptical   = "optical"
+)
+
+type FilesystemConfig struct {
+	ExcludeRemovable   bool
+	ExcludeNetwork     bool
+	ExcludePseudo      bool
+	ExcludeMountpoints []string
+	ExcludeFstypes     []string
+	// Time-to-full forecasting from the used bytes history kept in the cache.
+	ForecastWindow     time.Duration
+	ForecastMinSamples int
+	ForecastMaxHours   float64
+	// ForecastEventHours raises an event when a volume is forecast to be full
+	// sooner than this. Zero disables the event.
+	ForecastEventHours float64
+}
+
+type diskForecast struct {
+	GrowthBytesPerHour float64
+	// HoursUntilFull is only set when usage is growing.
+	HoursUntilFull    float64
+	HasHoursUntilFull bool
+}
+
+type volumeUsage struct {
+	Mountpoint string
+	Fstype     string
+	Class      string
+	Usage      DiskUsage
+	// Inode counters are zero where the filesystem has no inodes, e.g. NTFS.
+	InodesTotal uint64
+	InodesUsed  uint64
+	InodesFree  uint64
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+var networkFstypes = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smbfs": true, "smb3": true,
+	"afs": true, "ceph": true, "glusterfs": true, "sshfs": true, "fuse.sshfs": true,
+	"9p": true, "webdav": true, "davfs": true,
+}
+
+var pseudoFstypes = map[string]bool{
+	"proc": true, "sysfs": true, "tmpfs": true, "devtmpfs": true, "devpts": true,
+	"cgroup": true, "cgroup2": true, "securityfs": true, "debugfs": true, "tracefs": true,
+	"pstore": true, "bpf": true, "configfs": true, "fusectl": true, "mqueue": true,
+	"hugetlbfs": true, "autofs": true, "binfmt_misc": true, "overlay": true,
+	"squashfs": true, "nsfs": true, "ramfs": true, "efivarfs": true, "devfs": true,
+}
+
+// classifyPartition infers the volume class from the filesystem type and, for
+// block devices, the removable flag in sysfs.
+func classifyPartition(partition disk.PartitionStat) string {
+	fstype := strings.ToLower(partition.Fstype)
+	switch {
+	case networkFstypes[fstype]:
+		return VolumeNetwork
+	case pseudoFstypes[fstype]:
+		return VolumePseudo
+	case fstype == "iso9660" || fstype == "udf":
+		return VolumeOptical
+	}
+	if strings.HasPrefix(partition.Device, "/dev/") {
+		name := filepath.Base(partition.Device)
+		for _, path := range []string{
+			filepath.Join("/sys/class/block", name, "removable"),
+			// The partition entry links into the directory of its parent disk.
+			"/sys/class/block/" + name + "/../removable",
+		} {
+			if flag, err := os.ReadFile(path); err == nil {
+				if strings.TrimSpace(string(flag)) == "1" {
+					return VolumeRemovable
+				}
+				break
+			}
+		}
+	}
+	return VolumeFixed
+}
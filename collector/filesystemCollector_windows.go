@@ -0,0 +1,27 @@
+//go:build windows
+
+package collector
+
+import (
+	"github.com/shirou/gopsutil/v4/disk"
+	"golang.org/x/sys/windows"
+)
+
+// classifyPartition maps the Windows drive type of a volume to a volume class.
+func classifyPartition(partition disk.PartitionStat) string {
+	rootPath, err := windows.UTF16PtrFromString(partition.Mountpoint + `\`)
+	if err != nil {
+		return VolumeFixed
+	}
+	switch windows.GetDriveType(rootPath) {
+	case windows.DRIVE_REMOVABLE:
+		return VolumeRemovable
+	case windows.DRIVE_REMOTE:
+		return VolumeNetwork
+	case windows.DRIVE_CDROM:
+		return VolumeOptical
+	case windows.DRIVE_RAMDISK:
+		return VolumePseudo
+	}
+	return VolumeFixed
+}
@@ -3,21 +3,34 @@ package collector
 import (
 	"fmt"
 	"log"
+	"log/slog"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/load"
 	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/shirou/gopsutil/v4/net"
-	"github.com/shirou/gopsutil/v4/process"
 )
 
 type GeneralCollector struct {
+	// CPU time counters from the previous GetCPUMetrics call.
+	prevCPUTimes    *cpu.TimesStat
+	prevPerCPUTimes map[string]cpu.TimesStat
+	// Swap counters from the previous GetMemoryMetrics call.
+	prevSwap     *mem.SwapMemoryStat
+	prevSwapTime time.Time
 }
 
 func CreateGeneralCollector() *GeneralCollector {
-	return &GeneralCollector{}
+	return &GeneralCollector{
+		prevPerCPUTimes: make(map[string]cpu.TimesStat),
+	}
 }
 
 func (collector *GeneralCollector) GetMemoryMetrics() *map[string]map[string]float64 {
@@ -32,24 +45,144 @@ func (collector *GeneralCollector) GetMemoryMetrics() *map[string]map[string]flo
 	result[""]["Memory Available MB"] = float64(vmStat.Available) / 1024 / 1024
 	result[""]["Memory Used MB"] = float64(vmStat.Used) / 1024 / 1024
 	result[""]["Memory Usage %"] = vmStat.UsedPercent
+	// Cached, buffers and committed memory are not reported by every platform.
+	if vmStat.Cached > 0 {
+		result[""]["Memory Cached MB"] = float64(vmStat.Cached) / 1024 / 1024
+	}
+	if vmStat.Buffers > 0 {
+		result[""]["Memory Buffers MB"] = float64(vmStat.Buffers) / 1024 / 1024
+	}
+	if vmStat.CommittedAS > 0 {
+		result[""]["Memory Committed MB"] = float64(vmStat.CommittedAS) / 1024 / 1024
+	}
+
+	swapStat, err := mem.SwapMemory()
+	if err != nil {
+		slog.Error("Error fetching swap memory info", "error", err)
+		return &result
+	}
+	result[""]["Swap Total MB"] = float64(swapStat.Total) / 1024 / 1024
+	result[""]["Swap Used MB"] = float64(swapStat.Used) / 1024 / 1024
+	result[""]["Swap Free MB"] = float64(swapStat.Free) / 1024 / 1024
+	result[""]["Swap Usage %"] = swapStat.UsedPercent
+
+	// Swap-in/out counters are not available on Windows, where the paging rate
+	// and memory pressure come from the PDH memory counters instead.
+	now := time.Now()
+	if runtime.GOOS != "windows" && collector.prevSwap != nil {
+		seconds := now.Sub(collector.prevSwapTime).Seconds()
+		swapInBytesPerSec := counterRate(swapStat.Sin, collector.prevSwap.Sin, seconds)
+		swapOutBytesPerSec := counterRate(swapStat.Sout, collector.prevSwap.Sout, seconds)
+		result[""]["Swap In Bytes/s"] = swapInBytesPerSec
+		result[""]["Swap Out Bytes/s"] = swapOutBytesPerSec
+		pagesPerSec := (swapInBytesPerSec + swapOutBytesPerSec) / float64(os.Getpagesize())
+		result[""]["Memory Pressure"] = memoryPressure(vmStat.UsedPercent, pagesPerSec)
+	}
+	collector.prevSwap = swapStat
+	collector.prevSwapTime = now
 
 	return &result
 }
 
+// memoryPressure combines memory usage and paging into a 0-100 indicator. It is
+// driven by whichever is worse: usage above MemoryPressureUsageStart percent,
+// or paging approaching MemoryPressurePagingSaturation pages per second.
+func memoryPressure(usedPercent float64, pagesPerSec float64) float64 {
+	usage := (usedPercent - MemoryPressureUsageStart) / (100 - MemoryPressureUsageStart)
+	paging := pagesPerSec / MemoryPressurePagingSaturation
+	return 100 * math.Min(1, math.Max(0, math.Max(usage, paging)))
+}
+
+// GetCPUMetrics reports aggregate and per-core usage as deltas of the CPU time
+// counters since the previous call, so it does not block. The first call only
+// records the counters and reports the core counts and load averages.
 func (collector *GeneralCollector) GetCPUMetrics() *map[string]map[string]float64 {
 	result := make(map[string]map[string]float64)
 	result[""] = make(map[string]float64)
 
-	cpuPercentData, err := cpu.Percent(time.Second, false)
+	if logicalCores, err := cpu.Counts(true); err == nil {
+		result[""]["CPU Logical Cores"] = float64(logicalCores)
+	}
+	if physicalCores, err := cpu.Counts(false); err == nil {
+		result[""]["CPU Physical Cores"] = float64(physicalCores)
+	}
+	// Load averages are not available on every platform.
+	if loadAvg, err := load.Avg(); err == nil {
+		result[""]["CPU Load 1m"] = loadAvg.Load1
+		result[""]["CPU Load 5m"] = loadAvg.Load5
+		result[""]["CPU Load 15m"] = loadAvg.Load15
+	}
+
+	totalTimes, err := cpu.Times(false)
+	if err != nil || len(totalTimes) == 0 {
+		slog.Error("Error fetching CPU times", "error", err)
+		return &result
+	}
+	if collector.prevCPUTimes != nil {
+		if breakdown, ok := cpuTimeBreakdown(*collector.prevCPUTimes, totalTimes[0]); ok {
+			for metric, value := range breakdown {
+				result[""][metric] = value
+			}
+		}
+	}
+	collector.prevCPUTimes = &totalTimes[0]
+
+	perCPUTimes, err := cpu.Times(true)
 	if err != nil {
-		log.Fatalf("Error fetching CPU percent data: %v", err)
+		slog.Error("Error fetching per-CPU times", "error", err)
 		return &result
 	}
-	result[""]["CPU Usage %"] = cpuPercentData[0]
+	for index, current := range perCPUTimes {
+		previous, ok := collector.prevPerCPUTimes[current.CPU]
+		collector.prevPerCPUTimes[current.CPU] = current
+		if !ok {
+			continue
+		}
+		breakdown, ok := cpuTimeBreakdown(previous, current)
+		if !ok {
+			continue
+		}
+		coreName := "CPU " + strconv.Itoa(index)
+		result[coreName] = map[string]float64{
+			"CPU Usage %":  breakdown["CPU Usage %"],
+			"CPU User %":   breakdown["CPU User %"],
+			"CPU System %": breakdown["CPU System %"],
+			"CPU Idle %":   breakdown["CPU Idle %"],
+		}
+	}
 
 	return &result
 }
 
+// cpuTimeBreakdown turns two samples of the CPU time counters into the share of
+// the elapsed time spent in each state.
+func cpuTimeBreakdown(previous cpu.TimesStat, current cpu.TimesStat) (map[string]float64, bool) {
+	// Guest time is already part of user time.
+	total := func(t cpu.TimesStat) float64 {
+		return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+	}
+	elapsed := total(current) - total(previous)
+	if elapsed <= 0 {
+		return nil, false
+	}
+	percent := func(currentValue float64, previousValue float64) float64 {
+		return math.Min(100, math.Max(0, (currentValue-previousValue)/elapsed*100))
+	}
+	idle := percent(current.Idle, previous.Idle)
+	iowait := percent(current.Iowait, previous.Iowait)
+	return map[string]float64{
+		"CPU Usage %":   math.Max(0, 100-idle-iowait),
+		"CPU User %":    percent(current.User, previous.User),
+		"CPU System %":  percent(current.System, previous.System),
+		"CPU Idle %":    idle,
+		"CPU Nice %":    percent(current.Nice, previous.Nice),
+		"CPU IOWait %":  iowait,
+		"CPU IRQ %":     percent(current.Irq, previous.Irq),
+		"CPU SoftIRQ %": percent(current.Softirq, previous.Softirq),
+		"CPU Steal %":   percent(current.Steal, previous.Steal),
+	}, true
+}
+
 func (collector *GeneralCollector) GetDiskMetrics() *map[string]map[string]float64 {
 	samplingTime := 2 * time.Second
 	result := make(map[string]map[string]float64)
@@ -208,39 +341,3 @@ func (collector *GeneralCollector) GetNetworkMetrics() *map[string]map[string]fl
 
 	return &result
 }
-
-func (collector *GeneralCollector) GetProcessMetrics() *map[string]map[string]float64 {
-	result := make(map[string]map[string]float64)
-	processes, err := process.Processes()
-	if err != nil {
-		log.Printf("Error getting processes: %v", err)
-	}
-
-	for _, p := range processes {
-		// Get the process name
-		name, err := p.Name()
-		if err != nil {
-			continue
-		}
-		if strings.TrimSpace(name) == "" {
-			continue
-		}
-
-		// Get CPU usage
-		cpuPercent, err := p.CPUPercent()
-		if err != nil {
-			continue
-		}
-
-		// Get memory usage
-		memInfo, err := p.MemoryInfo()
-		if err != nil {
-			continue
-		}
-		result[name] = make(map[string]float64)
-		result[name]["Process CPU Usage %"] = cpuPercent
-		result[name]["Process Memory Used MB"] = float64(memInfo.RSS) / 1024 / 1024
-	}
-
-	return &result
-}
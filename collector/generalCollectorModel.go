@@ -1,5 +1,10 @@
 package collector
 
+const (
+	MemoryPressureUsageStart       = 70.0
+	MemoryPressurePagingSaturation = 1000.0
+)
+
 type DiskUsage struct {
 	FreeBytes      uint64
 	TotalBytes     uint64
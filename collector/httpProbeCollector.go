@@ -0,0 +1,214 @@
+package collector
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+// httpProbeMaxBodyBytes caps the part of a response body that is read; the
+// response size is counted up to it.
+const httpProbeMaxBodyBytes = 10 * 1024 * 1024
+
+type HTTPProbeCollector struct {
+	config  HTTPProbeConfig
+	results map[string]httpProbeResult
+}
+
+func NewHTTPProbeCollector(config HTTPProbeConfig) *HTTPProbeCollector {
+	return &HTTPProbeCollector{config: config, results: make(map[string]httpProbeResult)}
+}
+
+// LoadHTTPProbeConfig reads the probe targets from [http_probe:<name>]
+// sections, for example:
+//
+//	[http_probe:Outlook]
+//	url = https://outlook.office365.com/owa/
+//	method = GET
+//	timeout = 10s
+//	expected_status = 200, 302
+//	follow_redirects = true
+//	insecure_skip_verify = false
+//	assert_contains = Sign in
+func LoadHTTPProbeConfig(p *configparser.ConfigParser) HTTPProbeConfig {
+	config := HTTPProbeConfig{}
+	for _, section := range getSectionsWithPrefix(p, HTTPProbeSectionPrefix) {
+		target := HTTPProbeTarget{
+			Name:               strings.TrimSpace(strings.TrimPrefix(section, HTTPProbeSectionPrefix)),
+			URL:                getConfigString(p, section, "url", ""),
+			Method:             strings.ToUpper(getConfigString(p, section, "method", http.MethodGet)),
+			Timeout:            getConfigDuration(p, section, "timeout", 10*time.Second),
+			FollowRedirects:    getConfigBool(p, section, "follow_redirects", true),
+			InsecureSkipVerify: getConfigBool(p, section, "insecure_skip_verify", false),
+			AssertContains:     getConfigString(p, section, "assert_contains", ""),
+		}
+		if target.URL == "" {
+			slog.Error("HTTP probe has no url, skipping it", "probe", target.Name)
+			continue
+		}
+		for _, status := range getConfigList(p, section, "expected_status") {
+			code, err := strconv.Atoi(status)
+			if err != nil {
+				slog.Error("Invalid expected_status for HTTP probe", "probe", target.Name, "error", err)
+				continue
+			}
+			target.ExpectedStatus = append(target.ExpectedStatus, code)
+		}
+		config.Targets = append(config.Targets, target)
+	}
+	return config
+}
+
+// Collect requests every target concurrently, so a slow target does not delay
+// the others beyond its own timeout.
+func (collector *HTTPProbeCollector) Collect() {
+	results := make(map[string]httpProbeResult)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, target := range collector.config.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := probeHTTP(target)
+			if err != nil {
+				slog.Warn("HTTP probe failed", "probe", target.Name, "url", target.URL, "error", err)
+			}
+			mutex.Lock()
+			results[target.Name] = result
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+	collector.results = results
+}
+
+// probeHTTP requests the target on a fresh connection, so every probe pays for
+// DNS, connect and TLS like a user opening the service does.
+func probeHTTP(target HTTPProbeTarget) (httpProbeResult, error) {
+	var result httpProbeResult
+	var mutex sync.Mutex
+	var dnsStart, connectStart, tlsStart, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			result.DNSTime += time.Since(dnsStart)
+		},
+		// Dual-stack dialing may race several connects; the winner counts.
+		ConnectStart: func(string, string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if connectStart.IsZero() {
+				connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err == nil && !connectStart.IsZero() {
+				result.ConnectTime += time.Since(connectStart)
+				connectStart = time.Time{}
+			}
+		},
+		TLSHandshakeStart: func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			result.TLSTime += time.Since(tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			firstByte = time.Now()
+		},
+	}
+
+	client := &http.Client{
+		Timeout: target.Timeout,
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: target.InsecureSkipVerify},
+		},
+	}
+	if !target.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	start := time.Now()
+	request, err := http.NewRequest(target.Method, target.URL, nil)
+	if err != nil {
+		return result, err
+	}
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), trace))
+	response, err := client.Do(request)
+	if err != nil {
+		result.TotalTime = time.Since(start)
+		return result, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, httpProbeMaxBodyBytes))
+	result.ResponseBytes = int64(len(body))
+	result.TotalTime = time.Since(start)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	result.StatusCode = response.StatusCode
+	if !firstByte.IsZero() {
+		result.FirstByteTime = firstByte.Sub(start)
+	}
+	if err != nil {
+		return result, err
+	}
+	if len(target.ExpectedStatus) > 0 && !slices.Contains(target.ExpectedStatus, response.StatusCode) {
+		return result, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	if len(target.ExpectedStatus) == 0 && response.StatusCode >= 400 {
+		return result, errors.New(response.Status)
+	}
+	if target.AssertContains != "" && !strings.Contains(string(body), target.AssertContains) {
+		return result, fmt.Errorf("response does not contain %q", target.AssertContains)
+	}
+	result.Success = true
+	return result, nil
+}
+
+// GetProbeMetrics reports one instance per target, named after its section.
+func (collector *HTTPProbeCollector) GetProbeMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for name, probe := range collector.results {
+		result[name] = map[string]float64{
+			"HTTP DNS Time ms":           float64(probe.DNSTime.Microseconds()) / 1000,
+			"HTTP Connect Time ms":       float64(probe.ConnectTime.Microseconds()) / 1000,
+			"HTTP TLS Handshake ms":      float64(probe.TLSTime.Microseconds()) / 1000,
+			"HTTP Time To First Byte ms": float64(probe.FirstByteTime.Microseconds()) / 1000,
+			"HTTP Total Time ms":         float64(probe.TotalTime.Microseconds()) / 1000,
+			"HTTP Status Code":           float64(probe.StatusCode),
+			"HTTP Response Bytes":        float64(probe.ResponseBytes),
+			"HTTP Success":               boolMetric(probe.Success),
+		}
+	}
+	return &result
+}
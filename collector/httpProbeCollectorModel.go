@@ -0,0 +1,37 @@
+package collector
+
+import "time"
+
+const HTTPProbeSectionPrefix = "http_probe:"
+
+// HTTPProbeTarget is a URL requested on every collection. The probe succeeds
+// when the response status is one of ExpectedStatus, or below 400 when no
+// status is configured, and the body contains AssertContains when set.
+type HTTPProbeTarget struct {
+	Name               string
+	URL                string
+	Method             string
+	Timeout            time.Duration
+	ExpectedStatus     []int
+	FollowRedirects    bool
+	InsecureSkipVerify bool
+	AssertContains     string
+}
+
+type HTTPProbeConfig struct {
+	Targets []HTTPProbeTarget
+}
+
+// httpProbeResult holds the timings of one request. The DNS, connect and TLS
+// times are summed over redirects; the time to first byte is the one of the
+// final response.
+type httpProbeResult struct {
+	DNSTime       time.Duration
+	ConnectTime   time.Duration
+	TLSTime       time.Duration
+	FirstByteTime time.Duration
+	TotalTime     time.Duration
+	StatusCode    int
+	ResponseBytes int64
+	Success       bool
+}
@@ -0,0 +1,154 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newProbeTestServer(t *testing.T, tls bool) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Write([]byte("<html>Sign in to continue</html>"))
+	})
+	mux.HandleFunc("/slow", func(writer http.ResponseWriter, _ *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		writer.Write([]byte("late"))
+	})
+	mux.HandleFunc("/missing", func(writer http.ResponseWriter, request *http.Request) {
+		http.NotFound(writer, request)
+	})
+	mux.HandleFunc("/redirect", func(writer http.ResponseWriter, request *http.Request) {
+		http.Redirect(writer, request, "/ok", http.StatusFound)
+	})
+	var server *httptest.Server
+	if tls {
+		server = httptest.NewTLSServer(mux)
+	} else {
+		server = httptest.NewServer(mux)
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProbeHTTP(t *testing.T) {
+	server := newProbeTestServer(t, false)
+	tests := []struct {
+		name        string
+		target      HTTPProbeTarget
+		wantSuccess bool
+		wantStatus  int
+		wantBytes   int64
+	}{
+		{
+			name:        "success",
+			target:      HTTPProbeTarget{URL: server.URL + "/ok", FollowRedirects: true},
+			wantSuccess: true,
+			wantStatus:  http.StatusOK,
+			wantBytes:   int64(len("<html>Sign in to continue</html>")),
+		},
+		{
+			name:        "status above 400 fails without expected status",
+			target:      HTTPProbeTarget{URL: server.URL + "/missing", FollowRedirects: true},
+			wantSuccess: false,
+			wantStatus:  http.StatusNotFound,
+			wantBytes:   int64(len("404 page not found\n")),
+		},
+		{
+			name:        "expected status",
+			target:      HTTPProbeTarget{URL: server.URL + "/missing", FollowRedirects: true, ExpectedStatus: []int{404}},
+			wantSuccess: true,
+			wantStatus:  http.StatusNotFound,
+			wantBytes:   int64(len("404 page not found\n")),
+		},
+		{
+			name:        "redirect followed",
+			target:      HTTPProbeTarget{URL: server.URL + "/redirect", FollowRedirects: true},
+			wantSuccess: true,
+			wantStatus:  http.StatusOK,
+			wantBytes:   int64(len("<html>Sign in to continue</html>")),
+		},
+		{
+			name:        "redirect not followed",
+			target:      HTTPProbeTarget{URL: server.URL + "/redirect", ExpectedStatus: []int{302}},
+			wantSuccess: true,
+			wantStatus:  http.StatusFound,
+		},
+		{
+			name:        "content assertion passes",
+			target:      HTTPProbeTarget{URL: server.URL + "/ok", FollowRedirects: true, AssertContains: "Sign in"},
+			wantSuccess: true,
+			wantStatus:  http.StatusOK,
+			wantBytes:   int64(len("<html>Sign in to continue</html>")),
+		},
+		{
+			name:        "content assertion fails",
+			target:      HTTPProbeTarget{URL: server.URL + "/ok", FollowRedirects: true, AssertContains: "Welcome back"},
+			wantSuccess: false,
+			wantStatus:  http.StatusOK,
+			wantBytes:   int64(len("<html>Sign in to continue</html>")),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.target.Method = http.MethodGet
+			test.target.Timeout = 5 * time.Second
+			result, err := probeHTTP(test.target)
+			if result.Success != test.wantSuccess {
+				t.Errorf("Success = %v, want %v (error %v)", result.Success, test.wantSuccess, err)
+			}
+			if (err == nil) != test.wantSuccess {
+				t.Errorf("error = %v, want success %v", err, test.wantSuccess)
+			}
+			if result.StatusCode != test.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", result.StatusCode, test.wantStatus)
+			}
+			if test.wantBytes > 0 && result.ResponseBytes != test.wantBytes {
+				t.Errorf("ResponseBytes = %d, want %d", result.ResponseBytes, test.wantBytes)
+			}
+		})
+	}
+}
+
+func TestProbeHTTPTimings(t *testing.T) {
+	server := newProbeTestServer(t, true)
+	result, err := probeHTTP(HTTPProbeTarget{
+		URL:                server.URL + "/slow",
+		Method:             http.MethodGet,
+		Timeout:            5 * time.Second,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	// The server listens on an IP address, so there is no DNS lookup.
+	if result.DNSTime != 0 {
+		t.Errorf("DNSTime = %v, want 0", result.DNSTime)
+	}
+	if result.ConnectTime <= 0 {
+		t.Errorf("ConnectTime = %v, want > 0", result.ConnectTime)
+	}
+	if result.TLSTime <= 0 {
+		t.Errorf("TLSTime = %v, want > 0", result.TLSTime)
+	}
+	if result.FirstByteTime < 50*time.Millisecond {
+		t.Errorf("FirstByteTime = %v, want at least the 50ms the handler waits", result.FirstByteTime)
+	}
+	if result.TotalTime < result.FirstByteTime || result.FirstByteTime < result.ConnectTime+result.TLSTime {
+		t.Errorf("phases out of order: connect %v, tls %v, first byte %v, total %v",
+			result.ConnectTime, result.TLSTime, result.FirstByteTime, result.TotalTime)
+	}
+}
+
+func TestProbeHTTPRejectsUntrustedCertificate(t *testing.T) {
+	server := newProbeTestServer(t, true)
+	result, err := probeHTTP(HTTPProbeTarget{URL: server.URL + "/ok", Method: http.MethodGet, Timeout: 5 * time.Second})
+	if err == nil || result.Success {
+		t.Fatalf("probe of a self-signed server succeeded without insecure_skip_verify")
+	}
+	if !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("error = %v, want a certificate error", err)
+	}
+}
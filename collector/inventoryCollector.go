@@ -0,0 +1,162 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+type InventoryCollector struct {
+	agentVersion string
+	inventory    *HostInventory
+	events       []Event
+}
+
+func NewInventoryCollector(agentVersion string) *InventoryCollector {
+	return &InventoryCollector{agentVersion: agentVersion}
+}
+
+// Collect takes a new inventory snapshot. An event carrying the full inventory
+// is raised on the first call and whenever any field other than the boot time
+// changes afterwards.
+func (collector *InventoryCollector) Collect() {
+	inventory := HostInventory{AgentVersion: collector.agentVersion}
+	hostInfo, err := host.Info()
+	if err != nil {
+		slog.Error("Error fetching host info", "error", err)
+	}
+	if hostInfo != nil {
+		inventory.Hostname = hostInfo.Hostname
+		inventory.OS = hostInfo.OS
+		inventory.Platform = hostInfo.Platform
+		inventory.PlatformVersion = hostInfo.PlatformVersion
+		inventory.KernelVersion = hostInfo.KernelVersion
+		inventory.Architecture = hostInfo.KernelArch
+		inventory.BootTime = time.Unix(int64(hostInfo.BootTime), 0)
+		inventory.VirtualizationSystem = hostInfo.VirtualizationSystem
+		inventory.VirtualizationRole = hostInfo.VirtualizationRole
+		inventory.HostID = hostInfo.HostID
+	}
+	if inventory.VirtualizationSystem == "" {
+		inventory.VirtualizationSystem, inventory.VirtualizationRole = detectVirtualization()
+	}
+	if cpuInfo, err := cpu.Info(); err == nil && len(cpuInfo) > 0 {
+		inventory.CPUModel = cpuInfo[0].ModelName
+	}
+	if logicalCores, err := cpu.Counts(true); err == nil {
+		inventory.CPULogicalCores = logicalCores
+	}
+	if vmStat, err := mem.VirtualMemory(); err == nil {
+		inventory.TotalMemoryBytes = vmStat.Total
+	}
+
+	changed := inventoryChanges(collector.inventory, &inventory)
+	if collector.inventory == nil || len(changed) > 0 {
+		message := "Host inventory at agent start"
+		if collector.inventory != nil {
+			message = fmt.Sprintf("Host inventory changed: %v", changed)
+		}
+		data := inventory.toMap()
+		data["changed"] = changed
+		collector.events = append(collector.events, Event{
+			Timestamp: time.Now(),
+			Device:    "",
+			Type:      EventHostInventory,
+			Message:   message,
+			Data:      data,
+		})
+	}
+	collector.inventory = &inventory
+}
+
+// inventoryChanges lists the fields that differ between two snapshots. Boot
+// time changes on every reboot and is tracked separately.
+func inventoryChanges(previous *HostInventory, current *HostInventory) []string {
+	changed := make([]string, 0)
+	if previous == nil {
+		return changed
+	}
+	previousValue := reflect.ValueOf(*previous)
+	currentValue := reflect.ValueOf(*current)
+	for i := 0; i < previousValue.NumField(); i++ {
+		name := previousValue.Type().Field(i).Name
+		if name == "BootTime" {
+			continue
+		}
+		if !reflect.DeepEqual(previousValue.Field(i).Interface(), currentValue.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// virtualizationVendors maps a lowercase substring of the system manufacturer
+// or model to the virtualization system, named like gopsutil names them.
+var virtualizationVendors = []struct {
+	Match  string
+	System string
+}{
+	{"vmware", "vmware"},
+	{"virtualbox", "vbox"},
+	{"innotek", "vbox"},
+	{"qemu", "kvm"},
+	{"kvm", "kvm"},
+	{"amazon ec2", "kvm"},
+	{"google compute engine", "kvm"},
+	{"xen", "xen"},
+	{"parallels", "parallels"},
+}
+
+// classifyVirtualization tells the hypervisor of a guest from its system
+// manufacturer and model. Hyper-V, which Azure also uses, reports Microsoft as
+// the manufacturer and "Virtual Machine" as the model. Hosts are not detected,
+// so the role is either "guest" or empty.
+func classifyVirtualization(manufacturer string, model string) (string, string) {
+	manufacturer, model = strings.ToLower(manufacturer), strings.ToLower(model)
+	if strings.Contains(manufacturer, "microsoft") && strings.Contains(model, "virtual machine") {
+		return "hyperv", "guest"
+	}
+	for _, vendor := range virtualizationVendors {
+		if strings.Contains(manufacturer, vendor.Match) || strings.Contains(model, vendor.Match) {
+			return vendor.System, "guest"
+		}
+	}
+	return "", ""
+}
+
+func (inventory *HostInventory) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"hostname":             inventory.Hostname,
+		"os":                   inventory.OS,
+		"platform":             inventory.Platform,
+		"platformVersion":      inventory.PlatformVersion,
+		"kernelVersion":        inventory.KernelVersion,
+		"architecture":         inventory.Architecture,
+		"cpuModel":             inventory.CPUModel,
+		"cpuLogicalCores":      inventory.CPULogicalCores,
+		"totalMemoryBytes":     inventory.TotalMemoryBytes,
+		"bootTime":             inventory.BootTime.UnixMilli(),
+		"virtualizationSystem": inventory.VirtualizationSystem,
+		"virtualizationRole":   inventory.VirtualizationRole,
+		"hostId":               inventory.HostID,
+		"agentVersion":         inventory.AgentVersion,
+	}
+}
+
+// GetInventory returns the latest snapshot, or nil before the first Collect.
+func (collector *InventoryCollector) GetInventory() *HostInventory {
+	return collector.inventory
+}
+
+// GetEvents returns the events detected since the last call and clears them.
+func (collector *InventoryCollector) GetEvents() []Event {
+	events := collector.events
+	collector.events = nil
+	return events
+}
@@ -0,0 +1,22 @@
+package collector
+
+import "time"
+
+// HostInventory describes the machine so anomalies can be sliced by OS build
+// or hardware model.
+type HostInventory struct {
+	Hostname             string
+	OS                   string
+	Platform             string
+	PlatformVersion      string
+	KernelVersion        string
+	Architecture         string
+	CPUModel             string
+	CPULogicalCores      int
+	TotalMemoryBytes     uint64
+	BootTime             time.Time
+	VirtualizationSystem string
+	VirtualizationRole   string
+	HostID               string
+	AgentVersion         string
+}
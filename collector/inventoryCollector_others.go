@@ -0,0 +1,9 @@
+//go:build !windows
+
+package collector
+
+// detectVirtualization has nothing to add where gopsutil fills the
+// virtualization fields itself.
+func detectVirtualization() (string, string) {
+	return "", ""
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package collector
+
+import "golang.org/x/sys/windows/registry"
+
+// detectVirtualization classifies the SMBIOS system manufacturer and model,
+// the values behind Win32_ComputerSystem, since gopsutil leaves the
+// virtualization fields empty on Windows.
+func detectVirtualization() (string, string) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `HARDWARE\DESCRIPTION\System\BIOS`, registry.QUERY_VALUE)
+	if err != nil {
+		return "", ""
+	}
+	defer key.Close()
+	manufacturer, _, _ := key.GetStringValue("SystemManufacturer")
+	model, _, _ := key.GetStringValue("SystemProductName")
+	return classifyVirtualization(manufacturer, model)
+}
@@ -0,0 +1,359 @@
+package collector
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"if-win-dex-agent/cache"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+// Offsets are persisted per source and file under this key prefix.
+const stateLogOffsetPrefix = "log.offset."
+
+const logFingerprintSize = 256
+
+type LogCollector struct {
+	config       LogConfig
+	stateService *cache.StateService
+	// files is keyed by source name and path. committed holds the positions
+	// whose entries were delivered, which are the ones kept in the state file.
+	files     map[string]*logFileState
+	committed map[string]logFileState
+	// started is set after the first scan, from when new files are read from
+	// their beginning.
+	started bool
+	entries []LogEntry
+}
+
+func NewLogCollector(config LogConfig, stateService *cache.StateService) *LogCollector {
+	return &LogCollector{
+		config:       config,
+		stateService: stateService,
+		files:        make(map[string]*logFileState),
+		committed:    make(map[string]logFileState),
+	}
+}
+
+// LoadLogConfig reads the tailed files from [log:<name>] sections and the
+// shared options from [log], for example:
+//
+//	[log]
+//	project = Win-Dex-Agent-Log
+//	max_entry_bytes = 32768
+//	max_read_bytes = 10485760
+//
+//	[log:App]
+//	paths = C:\App\logs\*.log, C:\App\error.log
+//	component = App
+//	multiline_pattern = ^\d{4}-\d{2}-\d{2}
+//	timestamp_pattern = ^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})
+//	timestamp_layout = 2006-01-02 15:04:05
+//	start_at_end = true
+func LoadLogConfig(p *configparser.ConfigParser) LogConfig {
+	config := LogConfig{
+		Project:       getConfigString(p, LogSectionName, "project", "Win-Dex-Agent-Log"),
+		MaxEntryBytes: getConfigInt(p, LogSectionName, "max_entry_bytes", 32*1024),
+		MaxReadBytes:  int64(getConfigInt(p, LogSectionName, "max_read_bytes", 10*1024*1024)),
+	}
+	for _, section := range getSectionsWithPrefix(p, LogSectionPrefix) {
+		source := LogSource{
+			Name:            strings.TrimSpace(strings.TrimPrefix(section, LogSectionPrefix)),
+			Paths:           getConfigList(p, section, "paths"),
+			Device:          getConfigString(p, section, "device", ""),
+			TimestampLayout: getConfigString(p, section, "timestamp_layout", time.RFC3339),
+			StartAtEnd:      getConfigBool(p, section, "start_at_end", true),
+		}
+		source.Component = getConfigString(p, section, "component", source.Name)
+		valid := true
+		for option, pattern := range map[string]**regexp.Regexp{
+			"multiline_pattern": &source.MultilinePattern,
+			"timestamp_pattern": &source.TimestampPattern,
+		} {
+			value := getConfigString(p, section, option, "")
+			if value == "" {
+				continue
+			}
+			re, err := regexp.Compile(value)
+			if err != nil {
+				slog.Error("Invalid log pattern, skipping the source", "source", source.Name, "option", option, "error", err)
+				valid = false
+				continue
+			}
+			*pattern = re
+		}
+		if valid && len(source.Paths) > 0 {
+			config.Sources = append(config.Sources, source)
+		}
+	}
+	return config
+}
+
+func (collector *LogCollector) Collect() {
+	seen := make(map[string]bool)
+	for _, source := range collector.config.Sources {
+		for _, pattern := range source.Paths {
+			paths, err := filepath.Glob(pattern)
+			if err != nil {
+				slog.Error("Invalid log path pattern", "source", source.Name, "pattern", pattern, "error", err)
+				continue
+			}
+			for _, path := range paths {
+				key := source.Name + "|" + path
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if err := collector.readFile(source, path, key); err != nil {
+					slog.Warn("Error reading log file", "source", source.Name, "path", path, "error", err)
+				}
+			}
+		}
+	}
+	// Forget files that disappeared; their offsets stay persisted.
+	for key := range collector.files {
+		if !seen[key] {
+			delete(collector.files, key)
+			delete(collector.committed, key)
+		}
+	}
+	collector.started = true
+}
+
+// readFile reads the entries appended since the last offset. A file that is
+// shorter than the offset was truncated and a file whose first bytes changed
+// was rotated; both are read from the start. The new offset is only saved by
+// CommitOffsets.
+func (collector *LogCollector) readFile(source LogSource, path string, key string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	state, known := collector.files[key]
+	if !known {
+		state, known = collector.loadState(key)
+		if !known {
+			state = &logFileState{}
+			if !collector.started && source.StartAtEnd {
+				state.Offset = size
+			}
+		}
+		collector.committed[key] = *state
+	}
+	// A state without a fingerprint was just created and has nothing to
+	// compare against yet.
+	if state.Fingerprint != "" && (size < state.Offset || !sameLogFile(file, state)) {
+		slog.Info("Log file was rotated or truncated, reading it from the start", "source", source.Name, "path", path)
+		state.Offset = 0
+		state.LastSize = 0
+		state.FingerprintSize = 0
+	}
+	collector.files[key] = state
+	if err := updateLogFingerprint(file, state, size); err != nil {
+		return err
+	}
+
+	if size == state.Offset {
+		state.LastSize = size
+		return nil
+	}
+	data := make([]byte, min(size-state.Offset, collector.config.MaxReadBytes))
+	n, err := file.ReadAt(data, state.Offset)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	data = data[:n]
+
+	// A pending entry at the end is only complete once the file stopped
+	// growing, or when it fills the whole read on its own.
+	idle := size == state.LastSize && state.Offset+int64(n) == size
+	consumed, entries := collector.parseEntries(source, data, idle)
+	if consumed == 0 && int64(n) == collector.config.MaxReadBytes {
+		consumed, entries = collector.parseEntries(source, data, true)
+	}
+	collector.entries = append(collector.entries, entries...)
+	state.Offset += int64(consumed)
+	state.LastSize = size
+	return nil
+}
+
+// parseEntries splits data into entries and returns how many bytes they
+// used. Unless flush is set, the trailing line without a newline and, for
+// multiline sources, the last entry are left for the next read.
+func (collector *LogCollector) parseEntries(source LogSource, data []byte, flush bool) (int, []LogEntry) {
+	type group struct {
+		start int
+		lines []string
+	}
+	groups := make([]group, 0)
+	end := 0
+	for end < len(data) {
+		newline := bytes.IndexByte(data[end:], '\n')
+		lineEnd := end + newline + 1
+		if newline < 0 {
+			if !flush {
+				break
+			}
+			lineEnd = len(data)
+		}
+		line := strings.TrimRight(string(data[end:lineEnd]), "\r\n")
+		if len(groups) == 0 || source.MultilinePattern == nil || source.MultilinePattern.MatchString(line) {
+			groups = append(groups, group{start: end})
+		}
+		groups[len(groups)-1].lines = append(groups[len(groups)-1].lines, line)
+		end = lineEnd
+	}
+	if source.MultilinePattern != nil && !flush && len(groups) > 0 {
+		end = groups[len(groups)-1].start
+		groups = groups[:len(groups)-1]
+	}
+
+	now := time.Now()
+	entries := make([]LogEntry, 0, len(groups))
+	for _, group := range groups {
+		message := strings.Join(group.lines, "\n")
+		if strings.TrimSpace(message) == "" {
+			continue
+		}
+		if collector.config.MaxEntryBytes > 0 && len(message) > collector.config.MaxEntryBytes {
+			message = truncateUTF8(message, collector.config.MaxEntryBytes)
+		}
+		entries = append(entries, LogEntry{
+			Timestamp: parseLogTimestamp(source, group.lines[0], now),
+			Device:    source.Device,
+			Component: source.Component,
+			Message:   message,
+		})
+	}
+	return end, entries
+}
+
+// truncateUTF8 cuts text to at most limit bytes without splitting a character.
+func truncateUTF8(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit]
+}
+
+func parseLogTimestamp(source LogSource, line string, defaultTime time.Time) time.Time {
+	if source.TimestampPattern == nil {
+		return defaultTime
+	}
+	match := source.TimestampPattern.FindStringSubmatch(line)
+	if len(match) < 2 {
+		return defaultTime
+	}
+	timestamp, err := time.ParseInLocation(source.TimestampLayout, match[1], time.Local)
+	if err != nil {
+		return defaultTime
+	}
+	return timestamp
+}
+
+func logFingerprint(file *os.File, size int64) (string, error) {
+	data := make([]byte, size)
+	if _, err := file.ReadAt(data, 0); err != nil && err != io.EOF {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func sameLogFile(file *os.File, state *logFileState) bool {
+	fingerprint, err := logFingerprint(file, state.FingerprintSize)
+	return err == nil && fingerprint == state.Fingerprint
+}
+
+// updateLogFingerprint extends the fingerprint of a file that was shorter
+// than logFingerprintSize when it was last taken.
+func updateLogFingerprint(file *os.File, state *logFileState, size int64) error {
+	fingerprintSize := min(size, logFingerprintSize)
+	if fingerprintSize == state.FingerprintSize && state.Fingerprint != "" {
+		return nil
+	}
+	fingerprint, err := logFingerprint(file, fingerprintSize)
+	if err != nil {
+		return err
+	}
+	state.Fingerprint = fingerprint
+	state.FingerprintSize = fingerprintSize
+	return nil
+}
+
+func (collector *LogCollector) loadState(key string) (*logFileState, bool) {
+	if collector.stateService == nil {
+		return nil, false
+	}
+	value, ok := collector.stateService.GetValue(stateLogOffsetPrefix + key)
+	if !ok {
+		return nil, false
+	}
+	state := &logFileState{}
+	if err := json.Unmarshal([]byte(value), state); err != nil {
+		slog.Error("Invalid saved log offset", "key", key, "error", err)
+		return nil, false
+	}
+	return state, true
+}
+
+func (collector *LogCollector) saveState(key string, state *logFileState) {
+	if collector.stateService == nil {
+		return
+	}
+	value, err := json.Marshal(state)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	collector.stateService.SetValue(stateLogOffsetPrefix+key, string(value))
+}
+
+// CommitOffsets saves the offsets of the files read since the last commit. It
+// is called once the entries read up to them were delivered, so entries are
+// not lost when the agent stops before sending them.
+func (collector *LogCollector) CommitOffsets() {
+	for key, state := range collector.files {
+		if committed, ok := collector.committed[key]; ok && committed == *state {
+			continue
+		}
+		collector.saveState(key, state)
+		collector.committed[key] = *state
+	}
+}
+
+// RollbackOffsets returns to the committed offsets after the entries could not
+// be delivered, so the next collection reads them again.
+func (collector *LogCollector) RollbackOffsets() {
+	for key := range collector.files {
+		state := collector.committed[key]
+		collector.files[key] = &state
+	}
+}
+
+// GetLogEntries returns the entries read since the last call and clears them.
+func (collector *LogCollector) GetLogEntries() []LogEntry {
+	entries := collector.entries
+	collector.entries = nil
+	return entries
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"regexp"
+	"time"
+)
+
+const LogSectionName = "log"
+const LogSectionPrefix = "log:"
+
+// LogSource is a set of files tailed as one component, from a [log:<name>]
+// section. Device follows the metric convention: empty for the host itself.
+type LogSource struct {
+	Name      string
+	Paths     []string
+	Device    string
+	Component string
+	// MultilinePattern matches the first line of an entry; other lines are
+	// appended to the entry before them. Without it every line is an entry.
+	MultilinePattern *regexp.Regexp
+	// TimestampPattern captures the timestamp of an entry in its first group,
+	// parsed with TimestampLayout. Entries without one use the read time.
+	TimestampPattern *regexp.Regexp
+	TimestampLayout  string
+	// StartAtEnd skips the existing content of files found at startup.
+	StartAtEnd bool
+}
+
+type LogConfig struct {
+	Sources []LogSource
+	// Project is the InsightFinder LOG project the entries are sent to.
+	Project string
+	// MaxEntryBytes truncates longer entries; MaxReadBytes bounds what is read
+	// from one file per collection, so a burst is shipped over several.
+	MaxEntryBytes int
+	MaxReadBytes  int64
+}
+
+// LogEntry is a log line, or a group of lines for multiline entries.
+type LogEntry struct {
+	Timestamp time.Time
+	Device    string
+	Component string
+	Message   string
+}
+
+// logFileState is the read position in a file. The fingerprint is a hash of
+// the first FingerprintSize bytes, which tells a rotated file with the same
+// name apart from the one the offset belongs to.
+type logFileState struct {
+	Offset          int64  `json:"offset"`
+	Fingerprint     string `json:"fingerprint"`
+	FingerprintSize int64  `json:"fingerprintSize"`
+	// LastSize is the size at the previous read, to tell an idle file whose
+	// last entry is complete from one that is still being written.
+	LastSize int64 `json:"lastSize"`
+}
@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"if-win-dex-agent/cache"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogCollectorOffsets(t *testing.T) {
+	directory := t.TempDir()
+	stateService, err := cache.CreateStateService(filepath.Join(directory, "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(directory, "app.log")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := LogConfig{
+		Sources:       []LogSource{{Name: "App", Paths: []string{path}, StartAtEnd: false}},
+		MaxEntryBytes: 1024,
+		MaxReadBytes:  1024,
+	}
+	messages := func(entries []LogEntry) []string {
+		result := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			result = append(result, entry.Message)
+		}
+		return result
+	}
+
+	collector := NewLogCollector(config, stateService)
+	collector.Collect()
+	if got := messages(collector.GetLogEntries()); len(got) != 1 || got[0] != "first" {
+		t.Fatalf("first read = %q, want [first]", got)
+	}
+
+	// A failed send reads the entries again.
+	collector.RollbackOffsets()
+	collector.Collect()
+	if got := messages(collector.GetLogEntries()); len(got) != 1 || got[0] != "first" {
+		t.Fatalf("read after rollback = %q, want [first]", got)
+	}
+
+	// Offsets read but not committed are not kept across restarts.
+	restarted := NewLogCollector(config, stateService)
+	restarted.Collect()
+	if got := messages(restarted.GetLogEntries()); len(got) != 1 {
+		t.Fatalf("read after restart without commit = %q, want [first]", got)
+	}
+	restarted.CommitOffsets()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("second\n")
+	file.Close()
+	restarted = NewLogCollector(config, stateService)
+	restarted.Collect()
+	if got := messages(restarted.GetLogEntries()); len(got) != 1 || got[0] != "second" {
+		t.Fatalf("read after restart with commit = %q, want [second]", got)
+	}
+}
+
+func TestLogCollectorStartAtEnd(t *testing.T) {
+	directory := t.TempDir()
+	existing := filepath.Join(directory, "existing.log")
+	if err := os.WriteFile(existing, []byte("old entry\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := LogConfig{
+		Sources:       []LogSource{{Name: "App", Paths: []string{filepath.Join(directory, "*.log")}, StartAtEnd: true}},
+		MaxEntryBytes: 1024,
+		MaxReadBytes:  1024,
+	}
+	collector := NewLogCollector(config, nil)
+	collector.Collect()
+	if entries := collector.GetLogEntries(); len(entries) != 0 {
+		t.Fatalf("first scan read %d entries from a file present at startup, want none", len(entries))
+	}
+
+	appendLog := func(path string, text string) {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.WriteString(text)
+		file.Close()
+	}
+	appendLog(existing, "new entry\n")
+	// A file created after startup is read from its beginning.
+	appendLog(filepath.Join(directory, "created.log"), "first entry\n")
+	collector.Collect()
+	got := make(map[string]bool)
+	for _, entry := range collector.GetLogEntries() {
+		got[entry.Message] = true
+	}
+	if len(got) != 2 || !got["new entry"] || !got["first entry"] {
+		t.Errorf("entries after startup = %v, want new entry and first entry", got)
+	}
+
+	// A rotated file is read from the start.
+	if err := os.WriteFile(existing, []byte("rotated entry\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	collector.Collect()
+	entries := collector.GetLogEntries()
+	if len(entries) != 1 || entries[0].Message != "rotated entry" {
+		t.Errorf("entries after rotation = %v, want the rotated entry", entries)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{text: "short", limit: 10, want: "short"},
+		{text: "abcdef", limit: 3, want: "abc"},
+		{text: "aé", limit: 2, want: "a"},
+		{text: "日本語", limit: 7, want: "日本"},
+		{text: "日本語", limit: 2, want: ""},
+	}
+	for _, test := range tests {
+		if got := truncateUTF8(test.text, test.limit); got != test.want {
+			t.Errorf("truncateUTF8(%q, %d) = %q, want %q", test.text, test.limit, got, test.want)
+		}
+	}
+}
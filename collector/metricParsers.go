@@ -0,0 +1,352 @@
+package collector
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Prometheus metric types from # TYPE lines. Samples of families without
+// one are untyped.
+const (
+	PrometheusCounter   = "counter"
+	PrometheusGauge     = "gauge"
+	PrometheusHistogram = "histogram"
+	PrometheusSummary   = "summary"
+	PrometheusUntyped   = "untyped"
+)
+
+// metricSample is a parsed line of Prometheus text. Type is the type of the
+// family the sample belongs to.
+type metricSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+	Type   string
+}
+
+// detectMetricFormat guesses the format of script output: JSON when it is an
+// object or array, Prometheus text when it has metadata comments or labels,
+// and the simple `metric{device} value` format otherwise.
+func detectMetricFormat(output string) string {
+	trimmed := strings.TrimSpace(output)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return ExecFormatJSON
+	}
+	if strings.Contains(output, "# TYPE ") || strings.Contains(output, "# HELP ") || strings.Contains(output, "=\"") {
+		return ExecFormatPrometheus
+	}
+	return ExecFormatSimple
+}
+
+// parseMetricOutput parses output in the given format into device → metric →
+// value. Metrics without a device are put under defaultDevice. Malformed
+// lines are skipped and reported in the returned error.
+func parseMetricOutput(output string, format string, defaultDevice string) (map[string]map[string]float64, error) {
+	if format == "" || format == ExecFormatAuto {
+		format = detectMetricFormat(output)
+	}
+	result := make(map[string]map[string]float64)
+	add := func(device string, metric string, value float64) {
+		if device == "" {
+			device = defaultDevice
+		}
+		if _, ok := result[device]; !ok {
+			result[device] = make(map[string]float64)
+		}
+		result[device][metric] = value
+	}
+
+	switch format {
+	case ExecFormatJSON:
+		return result, parseJSONMetrics(output, add)
+	case ExecFormatPrometheus:
+		samples, err := parsePrometheusText(output)
+		for _, sample := range samples {
+			if isHistogramBucket(sample) {
+				continue
+			}
+			device, metric := defaultPrometheusMapping(sample)
+			add(device, metric, sample.Value)
+		}
+		return result, err
+	case ExecFormatSimple:
+		return result, parseSimpleMetrics(output, add)
+	}
+	return result, fmt.Errorf("unknown metric format %q", format)
+}
+
+// parseSimpleMetrics reads `metric{device} value` lines. The metric name may
+// contain spaces and the device part is optional; # starts a comment.
+func parseSimpleMetrics(output string, add func(device string, metric string, value float64)) error {
+	var errs []error
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		split := strings.LastIndexAny(line, " \t")
+		if split < 0 {
+			errs = append(errs, fmt.Errorf("line %d: missing value", lineNumber))
+			continue
+		}
+		value, err := parseMetricValue(line[split+1:])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", lineNumber, err))
+			continue
+		}
+		metric, device := strings.TrimSpace(line[:split]), ""
+		if strings.HasSuffix(metric, "}") {
+			if open := strings.LastIndex(metric, "{"); open >= 0 {
+				metric, device = strings.TrimSpace(metric[:open]), strings.TrimSpace(metric[open+1:len(metric)-1])
+			}
+		}
+		if metric == "" {
+			errs = append(errs, fmt.Errorf("line %d: missing metric name", lineNumber))
+			continue
+		}
+		add(device, metric, value)
+	}
+	return errors.Join(errs...)
+}
+
+// parsePrometheusText reads samples in the Prometheus text exposition format.
+// Histograms and summaries come out as their _bucket, _sum and _count series
+// like they are written; timestamps are ignored.
+func parsePrometheusText(output string) ([]metricSample, error) {
+	samples := make([]metricSample, 0)
+	types := make(map[string]string)
+	var errs []error
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if fields := strings.Fields(line); len(fields) == 4 && fields[1] == "TYPE" {
+				types[fields[2]] = strings.ToLower(fields[3])
+			}
+			continue
+		}
+		sample, err := parsePrometheusLine(line)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", lineNumber, err))
+			continue
+		}
+		sample.Type = prometheusSampleType(sample.Name, types)
+		samples = append(samples, sample)
+	}
+	return samples, errors.Join(errs...)
+}
+
+// prometheusSampleType looks up the family of a sample, which for histograms
+// and summaries is the sample name without its series suffix.
+func prometheusSampleType(name string, types map[string]string) string {
+	if metricType, ok := types[name]; ok {
+		return metricType
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		if family, ok := strings.CutSuffix(name, suffix); ok {
+			if metricType := types[family]; metricType == PrometheusHistogram || metricType == PrometheusSummary {
+				return metricType
+			}
+		}
+	}
+	return PrometheusUntyped
+}
+
+func parsePrometheusLine(line string) (metricSample, error) {
+	sample := metricSample{Labels: make(map[string]string)}
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return sample, errors.New("missing value")
+	}
+	sample.Name = line[:end]
+	rest := line[end:]
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		rest, err = parsePrometheusLabels(rest[1:], sample.Labels)
+		if err != nil {
+			return sample, err
+		}
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return sample, errors.New("expected a value and an optional timestamp")
+	}
+	value, err := parseMetricValue(fields[0])
+	if err != nil {
+		return sample, err
+	}
+	sample.Value = value
+	return sample, nil
+}
+
+// parsePrometheusLabels reads `name="value",...}` into labels and returns
+// what follows the closing brace.
+func parsePrometheusLabels(text string, labels map[string]string) (string, error) {
+	for {
+		text = strings.TrimLeft(text, " \t")
+		if strings.HasPrefix(text, "}") {
+			return text[1:], nil
+		}
+		equals := strings.Index(text, "=")
+		if equals <= 0 {
+			return "", errors.New("malformed labels")
+		}
+		name := strings.TrimSpace(text[:equals])
+		text = strings.TrimLeft(text[equals+1:], " \t")
+		if !strings.HasPrefix(text, "\"") {
+			return "", fmt.Errorf("label %s is not quoted", name)
+		}
+		var value strings.Builder
+		i := 1
+		for ; i < len(text) && text[i] != '"'; i++ {
+			if text[i] == '\\' && i+1 < len(text) {
+				i++
+				switch text[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(text[i])
+				}
+				continue
+			}
+			value.WriteByte(text[i])
+		}
+		if i >= len(text) {
+			return "", fmt.Errorf("label %s is not terminated", name)
+		}
+		labels[name] = value.String()
+		text = strings.TrimLeft(text[i+1:], " \t")
+		text = strings.TrimPrefix(text, ",")
+	}
+}
+
+func isHistogramBucket(sample metricSample) bool {
+	return sample.Type == PrometheusHistogram && strings.HasSuffix(sample.Name, "_bucket")
+}
+
+// defaultPrometheusMapping returns the device and metric name of a sample not
+// matched by a rule: the quantile of a summary stays in the metric name and
+// the other labels form the device.
+func defaultPrometheusMapping(sample metricSample) (string, string) {
+	labels := make(map[string]string, len(sample.Labels))
+	for name, value := range sample.Labels {
+		if name != "quantile" {
+			labels[name] = value
+		}
+	}
+	return prometheusDevice(labels), appendMetricLabels(sample.Name, sample.Labels, []string{"quantile"})
+}
+
+// appendMetricLabels adds the labels the sample has to the metric name as
+// label=value.
+func appendMetricLabels(metric string, labels map[string]string, names []string) string {
+	for _, name := range names {
+		if value, ok := labels[name]; ok {
+			metric += " " + name + "=" + value
+		}
+	}
+	return metric
+}
+
+// prometheusDevice returns the device of a sample: its device label when it
+// has one, else the values of its labels in the order of the label names.
+func prometheusDevice(labels map[string]string) string {
+	if device, ok := labels["device"]; ok {
+		return device
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, labels[name])
+	}
+	return strings.Join(values, " ")
+}
+
+// parseJSONMetrics reads either an object of metrics, an object of devices
+// each holding an object of metrics, or an array of objects with metric,
+// device and value fields. Booleans are reported as 0 or 1.
+func parseJSONMetrics(output string, add func(device string, metric string, value float64)) error {
+	var document interface{}
+	if err := json.Unmarshal([]byte(output), &document); err != nil {
+		return err
+	}
+	var errs []error
+	switch document := document.(type) {
+	case map[string]interface{}:
+		for key, value := range document {
+			if metrics, ok := value.(map[string]interface{}); ok {
+				for metric, metricValue := range metrics {
+					if number, ok := jsonMetricValue(metricValue); ok {
+						add(key, metric, number)
+					} else {
+						errs = append(errs, fmt.Errorf("%s.%s is not a number", key, metric))
+					}
+				}
+			} else if number, ok := jsonMetricValue(value); ok {
+				add("", key, number)
+			} else {
+				errs = append(errs, fmt.Errorf("%s is not a number", key))
+			}
+		}
+	case []interface{}:
+		for index, item := range document {
+			record, ok := item.(map[string]interface{})
+			if !ok {
+				errs = append(errs, fmt.Errorf("item %d is not an object", index))
+				continue
+			}
+			metric, _ := record["metric"].(string)
+			device, _ := record["device"].(string)
+			number, ok := jsonMetricValue(record["value"])
+			if metric == "" || !ok {
+				errs = append(errs, fmt.Errorf("item %d needs a metric and a numeric value", index))
+				continue
+			}
+			add(device, metric, number)
+		}
+	default:
+		return errors.New("expected a JSON object or array")
+	}
+	return errors.Join(errs...)
+}
+
+func jsonMetricValue(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case bool:
+		return boolMetric(value), true
+	case string:
+		number, err := parseMetricValue(value)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// parseMetricValue parses a sample value. NaN and infinite values, valid in
+// Prometheus text, are rejected since they cannot be encoded as JSON.
+func parseMetricValue(text string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", text)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("non-finite value %q", text)
+	}
+	return value, nil
+}
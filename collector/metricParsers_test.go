@@ -0,0 +1,176 @@
+package collector
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+// collectMetrics returns an add function for the parsers and the device →
+// metric → value map it fills.
+func collectMetrics() (func(string, string, float64), map[string]map[string]float64) {
+	result := make(map[string]map[string]float64)
+	return func(device string, metric string, value float64) {
+		if _, ok := result[device]; !ok {
+			result[device] = make(map[string]float64)
+		}
+		result[device][metric] = value
+	}, result
+}
+
+func TestParseSimpleMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    map[string]map[string]float64
+		wantErr bool
+	}{
+		{
+			name:   "metrics with and without device",
+			output: "# queue state\nQueue Length 12\nQueue Length{Inbound} 3\n\nLatency ms{C:\\App}\t1.5e2\n",
+			want: map[string]map[string]float64{
+				"":        {"Queue Length": 12},
+				"Inbound": {"Queue Length": 3},
+				"C:\\App": {"Latency ms": 150},
+			},
+		},
+		{
+			name:    "malformed lines are skipped",
+			output:  "Valid 1\nnovalue\nBad value abc\n{Device} 2\nInfinite +Inf\n",
+			want:    map[string]map[string]float64{"": {"Valid": 1}},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			add, got := collectMetrics()
+			err := parseSimpleMetrics(test.output, add)
+			if (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want error %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("metrics = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePrometheusText(t *testing.T) {
+	output := `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",path="/a \"b\""} 1027 1395066363000
+http_requests_total{method="POST"} 3
+# TYPE queue_depth gauge
+queue_depth 4.5
+# TYPE request_seconds histogram
+request_seconds_bucket{le="0.5"} 10
+request_seconds_sum 3.2
+request_seconds_count 12
+untyped_value{ device = "disk0" , } 7
+broken{label=unquoted} 1
+nan_value NaN
+`
+	want := []metricSample{
+		{Name: "http_requests_total", Labels: map[string]string{"method": "GET", "path": `/a "b"`}, Value: 1027, Type: PrometheusCounter},
+		{Name: "http_requests_total", Labels: map[string]string{"method": "POST"}, Value: 3, Type: PrometheusCounter},
+		{Name: "queue_depth", Labels: map[string]string{}, Value: 4.5, Type: PrometheusGauge},
+		{Name: "request_seconds_bucket", Labels: map[string]string{"le": "0.5"}, Value: 10, Type: PrometheusHistogram},
+		{Name: "request_seconds_sum", Labels: map[string]string{}, Value: 3.2, Type: PrometheusHistogram},
+		{Name: "request_seconds_count", Labels: map[string]string{}, Value: 12, Type: PrometheusHistogram},
+		{Name: "untyped_value", Labels: map[string]string{"device": "disk0"}, Value: 7, Type: PrometheusUntyped},
+	}
+
+	samples, err := parsePrometheusText(output)
+	if err == nil {
+		t.Error("expected an error for the malformed lines")
+	}
+	if len(samples) != len(want) {
+		t.Fatalf("got %d samples, want %d: %+v", len(samples), len(want), samples)
+	}
+	for index, sample := range samples {
+		expected := want[index]
+		if sample.Name != expected.Name || sample.Value != expected.Value || sample.Type != expected.Type || !maps.Equal(sample.Labels, expected.Labels) {
+			t.Errorf("sample %d = %+v, want %+v", index, sample, expected)
+		}
+	}
+}
+
+func TestParseMetricOutputPrometheus(t *testing.T) {
+	output := `# TYPE req_seconds histogram
+req_seconds_bucket{le="0.5"} 10
+req_seconds_bucket{le="+Inf"} 12
+req_seconds_sum{handler="api"} 3.2
+req_seconds_count 12
+# TYPE rpc_seconds summary
+rpc_seconds{service="auth",quantile="0.99"} 0.25
+rpc_seconds{quantile="0.5"} 0.1
+disk_free{device="C:"} 50
+`
+	want := map[string]map[string]float64{
+		"script": {"req_seconds_count": 12, "rpc_seconds quantile=0.5": 0.1},
+		"api":    {"req_seconds_sum": 3.2},
+		"auth":   {"rpc_seconds quantile=0.99": 0.25},
+		"C:":     {"disk_free": 50},
+	}
+
+	result, err := parseMetricOutput(output, ExecFormatAuto, "script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.EqualFunc(result, want, maps.Equal) {
+		t.Errorf("got %v, want %v", result, want)
+	}
+}
+
+func TestParseJSONMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    map[string]map[string]float64
+		wantErr bool
+	}{
+		{
+			name:   "flat object",
+			output: `{"Queue Length": 12, "Healthy": true, "Latency ms": "1.5"}`,
+			want:   map[string]map[string]float64{"": {"Queue Length": 12, "Healthy": 1, "Latency ms": 1.5}},
+		},
+		{
+			name:   "object of devices",
+			output: `{"C:": {"Free GB": 20}, "D:": {"Free GB": 100, "Healthy": false}}`,
+			want: map[string]map[string]float64{
+				"C:": {"Free GB": 20},
+				"D:": {"Free GB": 100, "Healthy": 0},
+			},
+		},
+		{
+			name:    "array of records",
+			output:  `[{"metric": "Sessions", "device": "RDS1", "value": 4}, {"metric": "Sessions", "value": 9}, {"device": "RDS2", "value": 1}, "text"]`,
+			want:    map[string]map[string]float64{"RDS1": {"Sessions": 4}, "": {"Sessions": 9}},
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric values are skipped",
+			output:  `{"Version": "v1.2", "Count": 3, "Nested": {"Name": null}}`,
+			want:    map[string]map[string]float64{"": {"Count": 3}},
+			wantErr: true,
+		},
+		{
+			name:    "not JSON",
+			output:  `{"Count": `,
+			want:    map[string]map[string]float64{},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			add, got := collectMetrics()
+			err := parseJSONMetrics(test.output, add)
+			if (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want error %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("metrics = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+type NetworkInterfaceCollector struct {
+	lister     interfaceLister
+	interfaces []networkInterface
+	// Byte counters of the last two scans give the utilization, keyed by the
+	// same interface names.
+	counters        map[string]net.IOCountersStat
+	prevCounters    map[string]net.IOCountersStat
+	collectTime     time.Time
+	prevCollectTime time.Time
+	// Interfaces of the previous scan, to report status and address changes.
+	prevInterfaces map[string]networkInterface
+	events         []Event
+}
+
+func NewNetworkInterfaceCollector() *NetworkInterfaceCollector {
+	return &NetworkInterfaceCollector{lister: newInterfaceLister()}
+}
+
+func (collector *NetworkInterfaceCollector) Collect() {
+	interfaces, err := collector.lister.ListInterfaces()
+	if err != nil {
+		slog.Error("Error listing network interfaces", "error", err)
+	}
+	collector.interfaces = interfaces
+
+	collector.prevCounters = collector.counters
+	collector.prevCollectTime = collector.collectTime
+	collector.collectTime = time.Now()
+	collector.counters = make(map[string]net.IOCountersStat)
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		slog.Error("Error fetching network IOCounters", "error", err)
+	}
+	for _, counter := range counters {
+		collector.counters[counter.Name] = counter
+	}
+
+	collector.detectChanges()
+}
+
+// detectChanges reports interfaces that went up or down or whose addresses
+// changed since the previous scan, such as a VPN connecting or a new DHCP
+// lease. Nothing is reported for the very first scan.
+func (collector *NetworkInterfaceCollector) detectChanges() {
+	current := make(map[string]networkInterface)
+	for _, networkInterface := range collector.interfaces {
+		current[networkInterface.Name] = networkInterface
+	}
+	defer func() { collector.prevInterfaces = current }()
+	if collector.prevInterfaces == nil {
+		return
+	}
+
+	for name, networkInterface := range current {
+		previous, existed := collector.prevInterfaces[name]
+		if existed && previous.Up == networkInterface.Up && slices.Equal(previous.Addresses, networkInterface.Addresses) {
+			continue
+		}
+		status := "down"
+		if networkInterface.Up {
+			status = "up"
+		}
+		collector.events = append(collector.events, Event{
+			Timestamp: collector.collectTime,
+			Device:    name,
+			Type:      EventNetworkInterface,
+			Message:   fmt.Sprintf("Network interface %s is %s with addresses %s", name, status, strings.Join(networkInterface.Addresses, ", ")),
+			Data: map[string]interface{}{
+				"name":              name,
+				"description":       networkInterface.Description,
+				"type":              networkInterface.Type,
+				"up":                networkInterface.Up,
+				"addresses":         networkInterface.Addresses,
+				"previousUp":        previous.Up,
+				"previousAddresses": previous.Addresses,
+			},
+		})
+	}
+}
+
+// GetEvents returns the events detected since the last call and clears them.
+func (collector *NetworkInterfaceCollector) GetEvents() []Event {
+	events := collector.events
+	collector.events = nil
+	return events
+}
+
+func (collector *NetworkInterfaceCollector) GetInterfaceMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	seconds := collector.collectTime.Sub(collector.prevCollectTime).Seconds()
+	for _, networkInterface := range collector.interfaces {
+		metrics := map[string]float64{
+			"Network Interface Up":     boolMetric(networkInterface.Up),
+			"Network MTU":              float64(networkInterface.MTU),
+			"Network Link Speed Mbps":  float64(networkInterface.SpeedBitsPerSec) / 1e6,
+			"Network IP Address Count": float64(len(networkInterface.Addresses)),
+			"Network Is Ethernet":      boolMetric(networkInterface.Type == InterfaceEthernet),
+			"Network Is Wi-Fi":         boolMetric(networkInterface.Type == InterfaceWiFi),
+			"Network Is Virtual":       boolMetric(networkInterface.Type == InterfaceVirtual),
+			"Network Is VPN":           boolMetric(networkInterface.Type == InterfaceVPN),
+		}
+
+		// Links are full duplex, so the busier direction is the utilization.
+		current, ok := collector.counters[networkInterface.Name]
+		previous, hasPrevious := collector.prevCounters[networkInterface.Name]
+		if ok && hasPrevious && networkInterface.SpeedBitsPerSec > 0 {
+			inBitsPerSec := counterRate(current.BytesRecv, previous.BytesRecv, seconds) * 8
+			outBitsPerSec := counterRate(current.BytesSent, previous.BytesSent, seconds) * 8
+			metrics["Network Utilization %"] = min(max(inBitsPerSec, outBitsPerSec)/float64(networkInterface.SpeedBitsPerSec)*100, 100)
+		}
+		result[networkInterface.Name] = metrics
+	}
+	return &result
+}
+
+func boolMetric(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// classifyInterfaceName guesses the interface type from well-known adapter
+// descriptions and Unix interface name prefixes, for interfaces the platform
+// does not type precisely.
+func classifyInterfaceName(name string, description string) string {
+	text := strings.ToLower(name + " " + description)
+	name = strings.ToLower(name)
+	classes := []struct {
+		interfaceType string
+		keywords      []string
+		prefixes      []string
+	}{
+		{InterfaceTunnel, []string{"teredo", "isatap", "6to4", "ip-https"}, []string{"sit", "tunl", "ip6tnl", "gre"}},
+		{InterfaceVPN, []string{"vpn", "wireguard", "tap-windows", "anyconnect", "globalprotect", "fortinet"}, []string{"tun", "tap", "utun", "wg", "ppp", "ipsec"}},
+		{InterfaceVirtual, []string{"virtual", "hyper-v", "vmware", "virtualbox", "vethernet", "docker", "wsl"}, []string{"veth", "docker", "br-", "virbr", "vmnet", "vboxnet", "bridge", "cni", "flannel"}},
+		{InterfaceWiFi, []string{"wi-fi", "wifi", "wireless", "wlan", "802.11"}, []string{"wl"}},
+		{InterfaceEthernet, []string{"ethernet"}, []string{"eth", "en"}},
+	}
+	for _, class := range classes {
+		for _, keyword := range class.keywords {
+			if strings.Contains(text, keyword) {
+				return class.interfaceType
+			}
+		}
+		for _, prefix := range class.prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return class.interfaceType
+			}
+		}
+	}
+	return InterfaceOther
+}
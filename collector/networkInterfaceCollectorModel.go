@@ -0,0 +1,31 @@
+package collector
+
+// Interface types, so Wi-Fi, wired, virtual and VPN links can be told apart.
+// Tunnels are IPv6 transition pseudo-interfaces such as Teredo and ISATAP.
+const (
+	InterfaceEthernet = "ethernet"
+	InterfaceWiFi     = "wifi"
+	InterfaceVirtual  = "virtual"
+	InterfaceVPN      = "vpn"
+	InterfaceTunnel   = "tunnel"
+	InterfaceOther    = "other"
+)
+
+// networkInterface is the link metadata of a network interface. Name is the
+// name users see, which is the friendly name on Windows. SpeedBitsPerSec is
+// zero when the link speed is unknown.
+type networkInterface struct {
+	Name            string
+	Description     string
+	Type            string
+	Up              bool
+	MTU             int
+	SpeedBitsPerSec uint64
+	Addresses       []string
+}
+
+// interfaceLister enumerates the network interfaces of the machine, loopback
+// excluded, so the metric mapping works with any source of interfaces.
+type interfaceLister interface {
+	ListInterfaces() ([]networkInterface, error)
+}
@@ -0,0 +1,56 @@
+//go:build !windows
+
+package collector
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// netInterfaceLister lists the interfaces through gopsutil. The link speed
+// and wireless type come from sysfs where the platform has it.
+type netInterfaceLister struct{}
+
+func newInterfaceLister() interfaceLister {
+	return &netInterfaceLister{}
+}
+
+func (lister *netInterfaceLister) ListInterfaces() ([]networkInterface, error) {
+	stats, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := make([]networkInterface, 0, len(stats))
+	for _, stat := range stats {
+		if slices.Contains(stat.Flags, "loopback") {
+			continue
+		}
+		networkInterface := networkInterface{
+			Name: stat.Name,
+			Up:   slices.Contains(stat.Flags, "up"),
+			MTU:  stat.MTU,
+			Type: classifyInterfaceName(stat.Name, ""),
+		}
+		if _, err := os.Stat("/sys/class/net/" + stat.Name + "/wireless"); err == nil {
+			networkInterface.Type = InterfaceWiFi
+		}
+		// Virtual links report an unknown speed of -1.
+		if data, err := os.ReadFile("/sys/class/net/" + stat.Name + "/speed"); err == nil {
+			if mbps, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil && mbps > 0 {
+				networkInterface.SpeedBitsPerSec = uint64(mbps) * 1e6
+			}
+		}
+		for _, address := range stat.Addrs {
+			ip, _, _ := strings.Cut(address.Addr, "/")
+			networkInterface.Addresses = append(networkInterface.Addresses, ip)
+		}
+		slices.Sort(networkInterface.Addresses)
+		interfaces = append(interfaces, networkInterface)
+	}
+	return interfaces, nil
+}
@@ -0,0 +1,103 @@
+//go:build windows
+
+package collector
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// adapterInterfaceLister reads the adapters from GetAdaptersAddresses, which
+// carries the interface type, link speed and addresses in one call.
+type adapterInterfaceLister struct{}
+
+func newInterfaceLister() interfaceLister {
+	return &adapterInterfaceLister{}
+}
+
+func (lister *adapterInterfaceLister) ListInterfaces() ([]networkInterface, error) {
+	adapters, err := adapterAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := make([]networkInterface, 0, len(adapters))
+	for _, adapter := range adapters {
+		if adapter.IfType == windows.IF_TYPE_SOFTWARE_LOOPBACK {
+			continue
+		}
+		networkInterface := networkInterface{
+			Name:            windows.UTF16PtrToString(adapter.FriendlyName),
+			Description:     windows.UTF16PtrToString(adapter.Description),
+			Up:              adapter.OperStatus == windows.IfOperStatusUp,
+			MTU:             int(adapter.Mtu),
+			SpeedBitsPerSec: max(adapter.TransmitLinkSpeed, adapter.ReceiveLinkSpeed),
+		}
+		// The link speed is all ones when it is unknown.
+		if networkInterface.SpeedBitsPerSec == ^uint64(0) {
+			networkInterface.SpeedBitsPerSec = 0
+		}
+		networkInterface.Type = classifyInterfaceName(networkInterface.Name, networkInterface.Description)
+		switch adapter.IfType {
+		case windows.IF_TYPE_PPP:
+			networkInterface.Type = InterfaceVPN
+		case windows.IF_TYPE_TUNNEL:
+			// Teredo, ISATAP, 6to4 and IP-HTTPS are tunnels on every host;
+			// only tunnels named like a VPN client count as VPN.
+			if networkInterface.Type != InterfaceVPN {
+				networkInterface.Type = InterfaceTunnel
+			}
+		case windows.IF_TYPE_IEEE80211:
+			networkInterface.Type = InterfaceWiFi
+		}
+		for address := adapter.FirstUnicastAddress; address != nil; address = address.Next {
+			networkInterface.Addresses = append(networkInterface.Addresses, address.Address.IP().String())
+		}
+		slices.Sort(networkInterface.Addresses)
+		interfaces = append(interfaces, networkInterface)
+	}
+	return interfaces, nil
+}
+
+// adapterAddresses returns every network adapter, growing the buffer until
+// the list fits.
+func adapterAddresses() ([]*windows.IpAdapterAddresses, error) {
+	size := uint32(15000)
+	for {
+		buffer := make([]byte, size)
+		first := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buffer[0]))
+		err := windows.GetAdaptersAddresses(windows.AF_UNSPEC, windows.GAA_FLAG_INCLUDE_PREFIX, 0, first, &size)
+		if errors.Is(err, windows.ERROR_BUFFER_OVERFLOW) && size > uint32(len(buffer)) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var adapters []*windows.IpAdapterAddresses
+		for adapter := first; adapter != nil; adapter = adapter.Next {
+			adapters = append(adapters, adapter)
+		}
+		return adapters, nil
+	}
+}
+
+// interfaceNamesByInstance maps the instance names of the Network Interface
+// performance object, which are the adapter descriptions with reserved
+// characters replaced, to the interface names.
+func interfaceNamesByInstance() map[string]string {
+	result := make(map[string]string)
+	adapters, err := adapterAddresses()
+	if err != nil {
+		return result
+	}
+	replacer := strings.NewReplacer("(", "[", ")", "]", "#", "_", "/", "_", "\\", "_")
+	for _, adapter := range adapters {
+		instance := replacer.Replace(windows.UTF16PtrToString(adapter.Description))
+		result[instance] = windows.UTF16PtrToString(adapter.FriendlyName)
+	}
+	return result
+}
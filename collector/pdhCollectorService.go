@@ -5,18 +5,22 @@ import (
 	"log/slog"
 	"strconv"
 	"time"
+
+	"github.com/shirou/gopsutil/v4/mem"
 )
 
 const TicksToSecondScaleFactor = 1 / 1e7
 
 type PdhCollectorService struct {
-	memoryData       []memoryData
+	memoryDataTick1  []memoryData
+	memoryDataTick2  []memoryData
 	diskDataTick1    []diskData
 	diskDataTick2    []diskData
 	thermalZoneData  []thermalZoneData
-	tcpData          []tcpData
 	networkDataTick1 []networkData
 	networkDataTick2 []networkData
+	// interfaceNames maps Network Interface instances to interface names.
+	interfaceNames map[string]string
 }
 
 func NewPdhCollectorService() *PdhCollectorService {
@@ -29,18 +33,30 @@ func (p *PdhCollectorService) Collect() {
 	physicalDiskDataCollector, _ := pdh.NewCollector[diskData]("PhysicalDisk", pdh.InstancesAll)
 	thermalZoneDataCollector, _ := pdh.NewCollector[thermalZoneData]("Thermal Zone Information", pdh.InstancesAll)
 	networkDataCollector, _ := pdh.NewCollector[networkData]("Network Interface", pdh.InstancesAll)
+	memoryDataCollector, _ := pdh.NewCollector[memoryData]("Memory", nil)
 
 	err = physicalDiskDataCollector.Collect(&p.diskDataTick1)
 	if err != nil {
 		slog.Error(err.Error())
 		p.diskDataTick1 = nil
 	}
+	// Memory counters share the ticks of the disk counters.
+	err = memoryDataCollector.Collect(&p.memoryDataTick1)
+	if err != nil {
+		slog.Error(err.Error())
+		p.memoryDataTick1 = nil
+	}
 	time.Sleep(1 * time.Second)
 	err = physicalDiskDataCollector.Collect(&p.diskDataTick2)
 	if err != nil {
 		slog.Error(err.Error())
 		p.diskDataTick2 = nil
 	}
+	err = memoryDataCollector.Collect(&p.memoryDataTick2)
+	if err != nil {
+		slog.Error(err.Error())
+		p.memoryDataTick2 = nil
+	}
 
 	err = thermalZoneDataCollector.Collect(&p.thermalZoneData)
 	if err != nil {
@@ -48,6 +64,7 @@ func (p *PdhCollectorService) Collect() {
 		p.thermalZoneData = nil
 	}
 
+	p.interfaceNames = interfaceNamesByInstance()
 	err = networkDataCollector.Collect(&p.networkDataTick1)
 	if err != nil {
 		slog.Error(err.Error())
@@ -114,18 +131,58 @@ func (p *PdhCollectorService) GetNetworkMetrics() *map[string]map[string]float64
 		return &result
 	}
 
-	for i, network1 := range p.networkDataTick1 {
-		network2 := p.networkDataTick2[i]
-		interfaceName := "Network Interface " + strconv.Itoa(i)
+	networkTick1 := make(map[string]networkData)
+	for _, network1 := range p.networkDataTick1 {
+		networkTick1[network1.Name] = network1
+	}
+	for _, network2 := range p.networkDataTick2 {
+		network1, ok := networkTick1[network2.Name]
+		if !ok {
+			continue
+		}
+		// Use the interface name shared with the other network metrics, and
+		// the instance name for adapters without one.
+		interfaceName, ok := p.interfaceNames[network2.Name]
+		if !ok {
+			interfaceName = network2.Name
+		}
 
 		// Calculate differences between ticks
 		receivedBytesPerSec := (network2.BytesReceivedPerSec - network1.BytesReceivedPerSec) / float64(time.Second.Seconds())
 		sentBytesPerSec := (network2.BytesSentPerSec - network1.BytesSentPerSec) / float64(time.Second.Seconds())
 
 		result[interfaceName] = map[string]float64{
-			"Received MB/s": receivedBytesPerSec / 1024 / 1024,
-			"Sent MB/s":     sentBytesPerSec / 1024 / 1024,
+			"Received MB/s":               receivedBytesPerSec / 1024 / 1024,
+			"Sent MB/s":                   sentBytesPerSec / 1024 / 1024,
+			"Network Output Queue Length": network2.OutputQueueLength,
 		}
 	}
 	return &result
 }
+
+func (p *PdhCollectorService) GetMemoryMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	if len(p.memoryDataTick1) == 0 || len(p.memoryDataTick2) == 0 {
+		return &result
+	}
+	memory1 := p.memoryDataTick1[0]
+	memory2 := p.memoryDataTick2[0]
+
+	pagesInputPerSec := (memory2.PagesInputPerSec - memory1.PagesInputPerSec) / float64(time.Second.Seconds())
+	pagesOutputPerSec := (memory2.PagesOutputPerSec - memory1.PagesOutputPerSec) / float64(time.Second.Seconds())
+	usedPercent := 0.0
+	if vmStat, err := mem.VirtualMemory(); err == nil {
+		usedPercent = vmStat.UsedPercent
+	}
+
+	result[""] = map[string]float64{
+		"Memory Committed MB":    memory2.CommittedBytes / 1024 / 1024,
+		"Memory Commit Limit MB": memory2.CommitLimit / 1024 / 1024,
+		"Memory Cached MB":       memory2.CacheBytes / 1024 / 1024,
+		"Page Faults/s":          (memory2.PageFaultsPerSec - memory1.PageFaultsPerSec) / float64(time.Second.Seconds()),
+		"Pages Input/s":          pagesInputPerSec,
+		"Pages Output/s":         pagesOutputPerSec,
+		"Memory Pressure":        memoryPressure(usedPercent, pagesInputPerSec+pagesOutputPerSec),
+	}
+	return &result
+}
@@ -0,0 +1,684 @@
+package collector
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"if-win-dex-agent/cache"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+type ProcessCollector struct {
+	config        ProcessConfig
+	processes     map[int32]processSnapshot
+	prevProcesses map[int32]processSnapshot
+	// Application name per PID for the current and previous scan.
+	applications     map[int32]string
+	prevApplications map[int32]string
+	// Lifecycle changes between the previous and the current scan.
+	applicationStarts   map[string]int
+	applicationExits    map[string]int
+	applicationRestarts map[string]int
+	restartHistory      map[string][]time.Time
+	crashLooping        map[string]bool
+	events              []Event
+	collectTime         time.Time
+	prevCollectTime     time.Time
+	topApplications     map[string]*userTopApplication
+	sockets             socketLister
+	// PID of the unexpected listener last reported per configured port.
+	portConflicts map[uint16]int32
+}
+
+// stateUsernameSalt is the key of the generated username salt.
+const stateUsernameSalt = "process.username_salt"
+
+// topApplicationScans is how many scans in a row an application has to lead
+// before a change of the top application of a user is reported.
+const topApplicationScans = 3
+
+func NewProcessCollector(config ProcessConfig, stateService *cache.StateService) *ProcessCollector {
+	if config.CollectUsername && config.UsernamePrivacy != UsernamePlain && config.UsernameSalt == "" {
+		config.UsernameSalt = usernameSalt(stateService)
+	}
+	return &ProcessCollector{
+		config:            config,
+		applicationStarts: make(map[string]int),
+		applicationExits:  make(map[string]int),
+		restartHistory:    make(map[string][]time.Time),
+		crashLooping:      make(map[string]bool),
+		topApplications:   make(map[string]*userTopApplication),
+		sockets:           newSocketLister(),
+		portConflicts:     make(map[uint16]int32),
+	}
+}
+
+// LoadProcessConfig reads the application catalog from [application:<name>]
+// sections, for example:
+//
+//	[application:Teams]
+//	exe_names = ms-teams.exe, msedgewebview2.exe
+//	path_globs = C:\Program Files\WindowsApps\MSTeams_*\*
+//	cmdline_regex = --type=renderer
+//	parent_names = ms-teams.exe
+//
+// Crash loop detection is tuned in the [process] section with
+// crash_loop_restarts and crash_loop_window, which also holds the collect_*
+// switches for the optional per-process metrics. The executables expected on
+// a well-known port are listed in [port:<number>] sections:
+//
+//	[port:3389]
+//	exe_names = svchost.exe
+func LoadProcessConfig(p *configparser.ConfigParser) ProcessConfig {
+	config := ProcessConfig{
+		CrashLoopRestarts: getConfigInt(p, ProcessSectionName, "crash_loop_restarts", 3),
+		CrashLoopWindow:   getConfigDuration(p, ProcessSectionName, "crash_loop_window", 15*time.Minute),
+		CollectThreads:    getConfigBool(p, ProcessSectionName, "collect_threads", false),
+		CollectHandles:    getConfigBool(p, ProcessSectionName, "collect_handles", false),
+		CollectIO:         getConfigBool(p, ProcessSectionName, "collect_io", false),
+		CollectPageFaults: getConfigBool(p, ProcessSectionName, "collect_page_faults", false),
+		CollectPriority:   getConfigBool(p, ProcessSectionName, "collect_priority", false),
+		CollectUsername:   getConfigBool(p, ProcessSectionName, "collect_username", false),
+		// Per-user attribution, with usernames kept as-is, hashed or pseudonymized.
+		CollectUserMetrics: getConfigBool(p, ProcessSectionName, "collect_user_metrics", false),
+		UsernamePrivacy:    strings.ToLower(getConfigString(p, ProcessSectionName, "username_privacy", UsernamePlain)),
+		UsernameSalt:       getConfigString(p, ProcessSectionName, "username_salt", ""),
+		CollectConnections: getConfigBool(p, ProcessSectionName, "collect_connections", false),
+		// Listening port changes are frequent, so their events are opt-in.
+		ListeningPortEvents: getConfigBool(p, ProcessSectionName, "listening_port_events", false),
+		PortOwners:          make(map[uint16][]string),
+	}
+	config.ProcessTreeMode = strings.ToLower(getConfigString(p, ProcessSectionName, "process_tree", ProcessTreeOff))
+	config.ProcessTreeAncestors = getConfigList(p, ProcessSectionName, "process_tree_ancestors")
+	switch config.ProcessTreeMode {
+	case ProcessTreeOff, ProcessTreeRoot, ProcessTreeAncestor:
+	default:
+		slog.Error("Unknown process_tree mode, disabling it", "value", config.ProcessTreeMode)
+		config.ProcessTreeMode = ProcessTreeOff
+	}
+	if config.CollectUserMetrics {
+		config.CollectUsername = true
+	}
+	switch config.UsernamePrivacy {
+	case UsernamePlain, UsernameHash, UsernamePseudonym:
+	default:
+		slog.Error("Unknown username_privacy, falling back to hash", "value", config.UsernamePrivacy)
+		config.UsernamePrivacy = UsernameHash
+	}
+	for _, section := range getSectionsWithPrefix(p, ApplicationSectionPrefix) {
+		application := Application{
+			Name:        strings.TrimSpace(strings.TrimPrefix(section, ApplicationSectionPrefix)),
+			ExeNames:    getConfigList(p, section, "exe_names"),
+			PathGlobs:   getConfigList(p, section, "path_globs"),
+			ParentNames: getConfigList(p, section, "parent_names"),
+		}
+		for _, pattern := range getConfigList(p, section, "cmdline_regex") {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				slog.Error("Invalid cmdline_regex for application", "application", application.Name, "error", err)
+				continue
+			}
+			application.CmdlinePatterns = append(application.CmdlinePatterns, re)
+		}
+		config.Applications = append(config.Applications, application)
+	}
+	for _, section := range getSectionsWithPrefix(p, PortSectionPrefix) {
+		port, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(section, PortSectionPrefix)), 10, 16)
+		if err != nil {
+			slog.Error("Invalid port section", "section", section, "error", err)
+			continue
+		}
+		config.PortOwners[uint16(port)] = getConfigList(p, section, "exe_names")
+	}
+	return config
+}
+
+func (collector *ProcessCollector) Collect() {
+	processes, err := process.Processes()
+	if err != nil {
+		slog.Error("Error getting processes", "error", err)
+	}
+
+	needsExe, needsCmdline := false, false
+	for _, application := range collector.config.Applications {
+		needsExe = needsExe || len(application.PathGlobs) > 0
+		needsCmdline = needsCmdline || len(application.CmdlinePatterns) > 0
+	}
+
+	snapshots := make(map[int32]processSnapshot)
+	for _, p := range processes {
+		name, err := p.Name()
+		if err != nil || strings.TrimSpace(name) == "" {
+			continue
+		}
+		cpuPercent, err := p.CPUPercent()
+		if err != nil {
+			continue
+		}
+		memInfo, err := p.MemoryInfo()
+		if err != nil {
+			continue
+		}
+
+		snapshot := processSnapshot{
+			Pid:        p.Pid,
+			Name:       name,
+			CPUPercent: cpuPercent,
+			RSS:        memInfo.RSS,
+		}
+		// NumThreads caches the parent PID on Windows, so query it first.
+		snapshot.NumThreads, _ = p.NumThreads()
+		snapshot.Ppid, _ = p.Ppid()
+		if createTime, err := p.CreateTime(); err == nil {
+			snapshot.CreateTime = time.UnixMilli(createTime)
+		}
+		if needsExe {
+			snapshot.Exe, _ = p.Exe()
+		}
+		if needsCmdline {
+			snapshot.Cmdline, _ = p.Cmdline()
+		}
+		collector.collectOptional(p, &snapshot)
+		snapshots[p.Pid] = snapshot
+	}
+	if collector.config.CollectConnections {
+		sockets, err := collector.sockets.ListSockets()
+		if err != nil {
+			slog.Error("Error listing process sockets", "error", err)
+		}
+		attachSockets(snapshots, sockets)
+	}
+
+	collector.prevProcesses = collector.processes
+	collector.prevApplications = collector.applications
+	collector.processes = snapshots
+	collector.applications = collector.matchApplications(snapshots)
+	collector.prevCollectTime = collector.collectTime
+	collector.collectTime = time.Now()
+	collector.detectLifecycle()
+	if collector.config.CollectUserMetrics {
+		collector.detectTopApplications()
+	}
+	if collector.config.CollectConnections && collector.config.ListeningPortEvents {
+		collector.detectListeningPortChanges()
+	}
+	collector.checkPortOwners()
+}
+
+// collectOptional fills the per-process values enabled in the config. Values
+// the platform does not support are left unset and not reported.
+func (collector *ProcessCollector) collectOptional(p *process.Process, snapshot *processSnapshot) {
+	if collector.config.CollectHandles {
+		snapshot.NumHandles, _ = p.NumFDs()
+	}
+	if collector.config.CollectIO {
+		if ioCounters, err := p.IOCounters(); err == nil {
+			snapshot.HasIO = true
+			snapshot.IOReadBytes = ioCounters.ReadBytes
+			snapshot.IOWriteBytes = ioCounters.WriteBytes
+			snapshot.IOReadCount = ioCounters.ReadCount
+			snapshot.IOWriteCount = ioCounters.WriteCount
+		}
+	}
+	if collector.config.CollectPageFaults {
+		readPageFaults(p, snapshot)
+	}
+	if collector.config.CollectPriority {
+		if nice, err := p.Nice(); err == nil {
+			snapshot.HasNice = true
+			snapshot.Nice = nice
+		}
+	}
+	if collector.config.CollectUsername {
+		if username, err := p.Username(); err == nil && username != "" {
+			snapshot.Username = collector.maskUsername(username)
+		}
+	}
+}
+
+// usernameSalt returns the salt stored in the state file, generating a random
+// one on first use. Without a state file the salt only lasts until the agent
+// restarts, so hashes and pseudonyms change with every restart.
+func usernameSalt(stateService *cache.StateService) string {
+	if stateService != nil {
+		if salt, ok := stateService.GetValue(stateUsernameSalt); ok && salt != "" {
+			return salt
+		}
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		panic(err)
+	}
+	salt := hex.EncodeToString(random)
+	if stateService != nil {
+		stateService.SetValue(stateUsernameSalt, salt)
+	} else {
+		slog.Warn("No state file for the username salt, hashed usernames change when the agent restarts")
+	}
+	return salt
+}
+
+// maskUsername applies the configured privacy mode. Hashes are salted, with
+// username_salt or a generated salt, so they cannot be reversed with a
+// dictionary of known account names.
+func (collector *ProcessCollector) maskUsername(username string) string {
+	if collector.config.UsernamePrivacy == UsernamePlain {
+		return username
+	}
+	sum := sha256.Sum256([]byte(collector.config.UsernameSalt + strings.ToLower(username)))
+	digest := hex.EncodeToString(sum[:])
+	if collector.config.UsernamePrivacy == UsernamePseudonym {
+		return "user-" + digest[:8]
+	}
+	return digest[:16]
+}
+
+// counterRate turns two samples of a cumulative counter into a per-second
+// rate, treating a counter reset as no activity.
+func counterRate(current uint64, previous uint64, seconds float64) float64 {
+	if current < previous || seconds <= 0 {
+		return 0
+	}
+	return float64(current-previous) / seconds
+}
+
+// isSameProcess tells a running process apart from a new process that reused
+// the PID of one that exited.
+func isSameProcess(previous processSnapshot, current processSnapshot) bool {
+	return previous.Name == current.Name && previous.CreateTime.Equal(current.CreateTime)
+}
+
+// detectLifecycle diffs the current scan against the previous one, counting
+// starts and exits per application and tracking restarts per executable for
+// crash loop detection. Nothing is reported for the very first scan.
+func (collector *ProcessCollector) detectLifecycle() {
+	collector.applicationStarts = make(map[string]int)
+	collector.applicationExits = make(map[string]int)
+	collector.applicationRestarts = make(map[string]int)
+	if collector.prevProcesses == nil {
+		return
+	}
+
+	// Latest creation time of the new and of the exited processes, the
+	// application of the latest new process, and the executables with a
+	// process that kept running, per executable name.
+	exeStarts := make(map[string]time.Time)
+	exeApplications := make(map[string]string)
+	exeExits := make(map[string]time.Time)
+	exeSurvivors := make(map[string]bool)
+	for pid, snapshot := range collector.processes {
+		if previous, existed := collector.prevProcesses[pid]; existed && isSameProcess(previous, snapshot) {
+			exeSurvivors[snapshot.Name] = true
+			continue
+		}
+		if snapshot.CreateTime.After(exeStarts[snapshot.Name]) || exeStarts[snapshot.Name].IsZero() {
+			exeStarts[snapshot.Name] = snapshot.CreateTime
+			exeApplications[snapshot.Name] = collector.applications[pid]
+		}
+		if name, ok := collector.applications[pid]; ok {
+			collector.applicationStarts[name]++
+			collector.addEvent(name, EventProcessStart, fmt.Sprintf("%s started (PID %d)", snapshot.Name, pid), snapshot)
+		}
+	}
+	for pid, previous := range collector.prevProcesses {
+		if current, exists := collector.processes[pid]; exists && isSameProcess(previous, current) {
+			continue
+		}
+		if previous.CreateTime.After(exeExits[previous.Name]) || exeExits[previous.Name].IsZero() {
+			exeExits[previous.Name] = previous.CreateTime
+		}
+		if name, ok := collector.prevApplications[pid]; ok {
+			collector.applicationExits[name]++
+			collector.addEvent(name, EventProcessExit, fmt.Sprintf("%s exited (PID %d)", previous.Name, pid), previous)
+		}
+	}
+
+	// A restart is every running instance of an executable exiting and a new
+	// one starting after the previous scan, at most one per scan. Executables
+	// such as chrome.exe or svchost.exe, which start and exit helper processes
+	// all the time, always keep some instance running and are not counted.
+	// Exit times are not known, so a replacement started just before the last
+	// instance exited still counts.
+	windowStart := collector.collectTime.Add(-collector.config.CrashLoopWindow)
+	restarted := make(map[string]bool)
+	for exe, started := range exeStarts {
+		exited, ok := exeExits[exe]
+		if !ok || exeSurvivors[exe] || !started.After(collector.prevCollectTime) || !started.After(exited) {
+			continue
+		}
+		restarted[exe] = true
+		if name := exeApplications[exe]; name != "" {
+			collector.applicationRestarts[name]++
+		}
+	}
+	for exe := range restarted {
+		if _, ok := collector.restartHistory[exe]; !ok {
+			collector.restartHistory[exe] = nil
+		}
+	}
+	for exe := range collector.restartHistory {
+		history := collector.restartHistory[exe]
+		if restarted[exe] {
+			history = append(history, collector.collectTime)
+		}
+		recent := make([]time.Time, 0, len(history))
+		for _, restartTime := range history {
+			if restartTime.After(windowStart) {
+				recent = append(recent, restartTime)
+			}
+		}
+		if len(recent) == 0 {
+			delete(collector.restartHistory, exe)
+			delete(collector.crashLooping, exe)
+			continue
+		}
+		collector.restartHistory[exe] = recent
+
+		looping := collector.config.CrashLoopRestarts > 0 && len(recent) >= collector.config.CrashLoopRestarts
+		if looping && !collector.crashLooping[exe] {
+			collector.events = append(collector.events, Event{
+				Timestamp: collector.collectTime,
+				Device:    exe,
+				Type:      EventProcessCrashLoop,
+				Message:   fmt.Sprintf("%s restarted %d times within %s", exe, len(recent), collector.config.CrashLoopWindow),
+				Data: map[string]interface{}{
+					"executable": exe,
+					"restarts":   len(recent),
+					"window":     collector.config.CrashLoopWindow.String(),
+				},
+			})
+		}
+		collector.crashLooping[exe] = looping
+	}
+}
+
+func (collector *ProcessCollector) addEvent(device string, eventType string, message string, snapshot processSnapshot) {
+	data := map[string]interface{}{
+		"pid":        snapshot.Pid,
+		"ppid":       snapshot.Ppid,
+		"name":       snapshot.Name,
+		"createTime": snapshot.CreateTime.UnixMilli(),
+	}
+	if snapshot.Username != "" {
+		data["user"] = snapshot.Username
+	}
+	collector.events = append(collector.events, Event{
+		Timestamp: collector.collectTime,
+		Device:    device,
+		Type:      eventType,
+		Message:   message,
+		Data:      data,
+	})
+}
+
+// GetEvents returns the events detected since the last call and clears them.
+func (collector *ProcessCollector) GetEvents() []Event {
+	events := collector.events
+	collector.events = nil
+	return events
+}
+
+// processAge returns the age of the process in seconds at the last scan.
+func (collector *ProcessCollector) processAge(snapshot processSnapshot) float64 {
+	if snapshot.CreateTime.IsZero() {
+		return 0
+	}
+	return collector.collectTime.Sub(snapshot.CreateTime).Seconds()
+}
+
+// matchApplications assigns each process to the first application in the
+// catalog that matches it.
+func (collector *ProcessCollector) matchApplications(snapshots map[int32]processSnapshot) map[int32]string {
+	result := make(map[int32]string)
+	for pid, snapshot := range snapshots {
+		parentName := ""
+		if parent, ok := snapshots[snapshot.Ppid]; ok && snapshot.Ppid != pid {
+			parentName = parent.Name
+		}
+		for _, application := range collector.config.Applications {
+			if application.matches(snapshot, parentName) {
+				result[pid] = application.Name
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (application *Application) matches(snapshot processSnapshot, parentName string) bool {
+	for _, exeName := range application.ExeNames {
+		if strings.EqualFold(exeName, snapshot.Name) {
+			return true
+		}
+	}
+	if snapshot.Exe != "" {
+		for _, glob := range application.PathGlobs {
+			if ok, _ := filepath.Match(strings.ToLower(glob), strings.ToLower(snapshot.Exe)); ok {
+				return true
+			}
+		}
+	}
+	if snapshot.Cmdline != "" {
+		for _, pattern := range application.CmdlinePatterns {
+			if pattern.MatchString(snapshot.Cmdline) {
+				return true
+			}
+		}
+	}
+	if parentName != "" {
+		for _, name := range application.ParentNames {
+			if strings.EqualFold(name, parentName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (collector *ProcessCollector) GetProcessMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for _, snapshot := range collector.processes {
+		result[snapshot.Name] = make(map[string]float64)
+		result[snapshot.Name]["Process CPU Usage %"] = snapshot.CPUPercent
+		result[snapshot.Name]["Process Memory Used MB"] = float64(snapshot.RSS) / 1024 / 1024
+		result[snapshot.Name]["Process Age s"] = collector.processAge(snapshot)
+		collector.addOptionalMetrics(result[snapshot.Name], snapshot)
+	}
+	return &result
+}
+
+func (collector *ProcessCollector) addOptionalMetrics(metrics map[string]float64, snapshot processSnapshot) {
+	if collector.config.CollectThreads {
+		metrics["Process Thread Count"] = float64(snapshot.NumThreads)
+	}
+	if collector.config.CollectHandles {
+		metrics["Process Handle Count"] = float64(snapshot.NumHandles)
+	}
+	if collector.config.CollectPriority && snapshot.HasNice {
+		metrics["Process Priority"] = float64(snapshot.Nice)
+	}
+	if collector.config.CollectConnections {
+		metrics["Process Listening Ports"] = float64(len(snapshot.ListeningPorts))
+		metrics["Process Established Connections"] = float64(snapshot.EstablishedConnections)
+		metrics["Process Remote Endpoints"] = float64(snapshot.RemoteEndpoints)
+	}
+
+	// Rates need the same process in the previous scan.
+	previous, ok := collector.prevProcesses[snapshot.Pid]
+	if !ok || !isSameProcess(previous, snapshot) {
+		return
+	}
+	seconds := collector.collectTime.Sub(collector.prevCollectTime).Seconds()
+	if collector.config.CollectIO && snapshot.HasIO && previous.HasIO {
+		metrics["Process IO Read Bytes/s"] = counterRate(snapshot.IOReadBytes, previous.IOReadBytes, seconds)
+		metrics["Process IO Write Bytes/s"] = counterRate(snapshot.IOWriteBytes, previous.IOWriteBytes, seconds)
+		metrics["Process IO Read Ops/s"] = counterRate(snapshot.IOReadCount, previous.IOReadCount, seconds)
+		metrics["Process IO Write Ops/s"] = counterRate(snapshot.IOWriteCount, previous.IOWriteCount, seconds)
+	}
+	if collector.config.CollectPageFaults && snapshot.HasPageFaults && previous.HasPageFaults {
+		metrics["Process Page Faults/s"] = counterRate(snapshot.PageFaults, previous.PageFaults, seconds)
+		if snapshot.HasFaultSplit && previous.HasFaultSplit {
+			metrics["Process Minor Page Faults/s"] = counterRate(snapshot.MinorFaults, previous.MinorFaults, seconds)
+			metrics["Process Major Page Faults/s"] = counterRate(snapshot.MajorFaults, previous.MajorFaults, seconds)
+		}
+	}
+}
+
+// GetCrashLoopMetrics reports every executable that restarted within the crash
+// loop window, so a recovering executable drops back to 0 before it is removed.
+func (collector *ProcessCollector) GetCrashLoopMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for exe, history := range collector.restartHistory {
+		crashLoop := 0.0
+		if collector.crashLooping[exe] {
+			crashLoop = 1
+		}
+		result[exe] = map[string]float64{
+			"Process Restarts In Window": float64(len(history)),
+			"Process Crash Loop":         crashLoop,
+		}
+	}
+	return &result
+}
+
+// GetApplicationMetrics reports one instance per catalog application, including
+// applications that currently have no running process.
+func (collector *ProcessCollector) GetApplicationMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for _, application := range collector.config.Applications {
+		result[application.Name] = map[string]float64{
+			"Application CPU Usage %":    0,
+			"Application Memory Used MB": 0,
+			"Application Process Count":  0,
+			"Application Thread Count":   0,
+			"Application Process Starts": float64(collector.applicationStarts[application.Name]),
+			"Application Process Exits":  float64(collector.applicationExits[application.Name]),
+			// Executables of the application restarted since the previous
+			// collection, as counted for crash loop detection.
+			"Application Restarts": float64(collector.applicationRestarts[application.Name]),
+		}
+	}
+
+	youngest := make(map[string]float64)
+	for pid, name := range collector.applications {
+		snapshot := collector.processes[pid]
+		metrics := result[name]
+		metrics["Application CPU Usage %"] += snapshot.CPUPercent
+		metrics["Application Memory Used MB"] += float64(snapshot.RSS) / 1024 / 1024
+		metrics["Application Process Count"]++
+		metrics["Application Thread Count"] += float64(snapshot.NumThreads)
+		age := collector.processAge(snapshot)
+		if current, ok := youngest[name]; !ok || age < current {
+			youngest[name] = age
+		}
+	}
+	for name, age := range youngest {
+		result[name]["Application Youngest Process Age s"] = age
+	}
+	return &result
+}
+
+// processUser returns the owner a process is aggregated under. Processes
+// whose owner cannot be resolved are grouped under "unknown".
+func processUser(snapshot processSnapshot) string {
+	if snapshot.Username == "" {
+		return "unknown"
+	}
+	return snapshot.Username
+}
+
+// detectTopApplications finds the application using the most CPU per user.
+// The top application is a name, so it is reported as its CPU share and
+// announced with an event once a new one has led for topApplicationScans
+// scans in a row, rather than on every short spike. Users without processes
+// left are forgotten.
+func (collector *ProcessCollector) detectTopApplications() {
+	applicationCPU := make(map[string]map[string]float64)
+	for pid, snapshot := range collector.processes {
+		user := processUser(snapshot)
+		if _, ok := applicationCPU[user]; !ok {
+			applicationCPU[user] = make(map[string]float64)
+		}
+		application, ok := collector.applications[pid]
+		if !ok {
+			application = snapshot.Name
+		}
+		applicationCPU[user][application] += snapshot.CPUPercent
+	}
+	for user := range collector.topApplications {
+		if _, ok := applicationCPU[user]; !ok {
+			delete(collector.topApplications, user)
+		}
+	}
+
+	for user, applications := range applicationCPU {
+		topApplication, topCPU := "", -1.0
+		for application, cpuPercent := range applications {
+			if cpuPercent > topCPU || (cpuPercent == topCPU && application < topApplication) {
+				topApplication, topCPU = application, cpuPercent
+			}
+		}
+		state, ok := collector.topApplications[user]
+		if !ok {
+			state = &userTopApplication{}
+			collector.topApplications[user] = state
+		}
+		state.CPUPercent = topCPU
+		if state.Candidate == topApplication {
+			state.Scans++
+		} else {
+			state.Candidate, state.Scans = topApplication, 1
+		}
+		if state.Reported != topApplication && state.Scans >= topApplicationScans {
+			state.Reported = topApplication
+			collector.events = append(collector.events, Event{
+				Timestamp: collector.collectTime,
+				Device:    user,
+				Type:      EventUserTopApplication,
+				Message:   fmt.Sprintf("Top application for %s is %s", user, topApplication),
+				Data: map[string]interface{}{
+					"user":        user,
+					"application": topApplication,
+					"cpuPercent":  topCPU,
+				},
+			})
+		}
+	}
+}
+
+// GetUserMetrics aggregates resource usage per process owner, with the CPU
+// share of the top application of each user.
+func (collector *ProcessCollector) GetUserMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	if !collector.config.CollectUserMetrics {
+		return &result
+	}
+	for _, snapshot := range collector.processes {
+		user := processUser(snapshot)
+		if _, ok := result[user]; !ok {
+			result[user] = map[string]float64{
+				"User CPU Usage %":    0,
+				"User Memory Used MB": 0,
+				"User Process Count":  0,
+			}
+		}
+		result[user]["User CPU Usage %"] += snapshot.CPUPercent
+		result[user]["User Memory Used MB"] += float64(snapshot.RSS) / 1024 / 1024
+		result[user]["User Process Count"]++
+	}
+	for user, state := range collector.topApplications {
+		if metrics, ok := result[user]; ok {
+			metrics["User Top Application CPU %"] = state.CPUPercent
+		}
+	}
+	return &result
+}
@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"regexp"
+	"time"
+)
+
+const ApplicationSectionPrefix = "application:"
+const ProcessSectionName = "process"
+const PortSectionPrefix = "port:"
+
+// Process tree aggregation modes.
+const (
+	ProcessTreeOff      = "off"
+	ProcessTreeRoot     = "root"
+	ProcessTreeAncestor = "ancestor"
+)
+
+// Username privacy modes for per-user attribution.
+const (
+	UsernamePlain     = "plain"
+	UsernameHash      = "hash"
+	UsernamePseudonym = "pseudonym"
+)
+
+// Application is a named business application from the catalog. A process
+// belongs to the application when any of the matchers apply.
+type Application struct {
+	Name            string
+	ExeNames        []string
+	PathGlobs       []string
+	CmdlinePatterns []*regexp.Regexp
+	ParentNames     []string
+}
+
+type ProcessConfig struct {
+	Applications []Application
+	// An executable restarting CrashLoopRestarts times within CrashLoopWindow
+	// is reported as a crash loop.
+	CrashLoopRestarts int
+	CrashLoopWindow   time.Duration
+	// Optional per-process metrics, each enabled separately.
+	CollectThreads    bool
+	CollectHandles    bool
+	CollectIO         bool
+	CollectPageFaults bool
+	CollectPriority   bool
+	CollectUsername   bool
+	// CollectUserMetrics reports per-user aggregates and implies CollectUsername.
+	CollectUserMetrics bool
+	UsernamePrivacy    string
+	UsernameSalt       string
+	// ProcessTreeMode selects how child processes are rolled up. In ancestor
+	// mode they are attributed to the nearest of ProcessTreeAncestors.
+	ProcessTreeMode      string
+	ProcessTreeAncestors []string
+	// CollectConnections attaches the listening ports and connection counts;
+	// ListeningPortEvents also reports every change of the listening ports.
+	CollectConnections  bool
+	ListeningPortEvents bool
+	// PortOwners maps a well-known port to the executables allowed to listen
+	// on it. Any other listener is reported as a port conflict.
+	PortOwners map[uint16][]string
+}
+
+type processSnapshot struct {
+	Pid        int32
+	Ppid       int32
+	Name       string
+	Exe        string
+	Cmdline    string
+	CPUPercent float64
+	RSS        uint64
+	NumThreads int32
+	CreateTime time.Time
+	// NumHandles is the handle count on Windows and the FD count elsewhere.
+	NumHandles   int32
+	HasIO        bool
+	IOReadBytes  uint64
+	IOWriteBytes uint64
+	IOReadCount  uint64
+	IOWriteCount uint64
+	// PageFaults is the total; the minor/major split is only known off Windows.
+	HasPageFaults bool
+	HasFaultSplit bool
+	PageFaults    uint64
+	MinorFaults   uint64
+	MajorFaults   uint64
+	HasNice       bool
+	Nice          int32
+	Username      string
+	// Sockets, filled in when CollectConnections is set.
+	ListeningPorts         []uint16
+	EstablishedConnections int
+	RemoteEndpoints        int
+}
+
+// userTopApplication tracks the application using the most CPU for a user.
+// Candidate has led for Scans scans in a row with CPUPercent at the last one;
+// Reported was announced last.
+type userTopApplication struct {
+	Reported   string
+	Candidate  string
+	Scans      int
+	CPUPercent float64
+}
+
+// processSocket is a TCP socket with the process that owns it. State uses
+// the normalized TCP states of the connection collector.
+type processSocket struct {
+	Pid        int32
+	State      string
+	LocalPort  uint16
+	RemoteAddr string
+}
+
+// socketLister reads the TCP sockets per process, so the per-process
+// aggregation works with any source of sockets.
+type socketLister interface {
+	ListSockets() ([]processSocket, error)
+	// ListeningPortOwners returns the PID listening on each of the ports.
+	// Ports nobody listens on are left out.
+	ListeningPortOwners(ports []uint16) (map[uint16]int32, error)
+}
@@ -0,0 +1,68 @@
+//go:build !windows
+
+package collector
+
+import (
+	"slices"
+
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// netSocketLister reads the sockets through gopsutil. Sockets of processes
+// owned by other users have no PID unless the agent runs privileged.
+type netSocketLister struct{}
+
+func newSocketLister() socketLister {
+	return &netSocketLister{}
+}
+
+func (lister *netSocketLister) ListSockets() ([]processSocket, error) {
+	connections, err := net.Connections("tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	sockets := make([]processSocket, 0, len(connections))
+	for _, connection := range connections {
+		socket := processSocket{
+			Pid:       connection.Pid,
+			State:     connection.Status,
+			LocalPort: uint16(connection.Laddr.Port),
+		}
+		if connection.Raddr.IP != "" && connection.Raddr.IP != "0.0.0.0" && connection.Raddr.IP != "::" {
+			socket.RemoteAddr = connection.Raddr.IP
+		}
+		sockets = append(sockets, socket)
+	}
+	return sockets, nil
+}
+
+func (lister *netSocketLister) ListeningPortOwners(ports []uint16) (map[uint16]int32, error) {
+	connections, err := net.Connections("tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint16]int32)
+	for _, connection := range connections {
+		port := uint16(connection.Laddr.Port)
+		if connection.Status == TCPStateListen && connection.Pid != 0 && slices.Contains(ports, port) {
+			result[port] = connection.Pid
+		}
+	}
+	return result, nil
+}
+
+// readPageFaults fills the page fault counters, split into minor and major.
+func readPageFaults(p *process.Process, snapshot *processSnapshot) {
+	pageFaults, err := p.PageFaults()
+	if err != nil {
+		return
+	}
+	snapshot.HasPageFaults = true
+	snapshot.HasFaultSplit = true
+	snapshot.MinorFaults = pageFaults.MinorFaults
+	snapshot.MajorFaults = pageFaults.MajorFaults
+	snapshot.PageFaults = pageFaults.MinorFaults + pageFaults.MajorFaults
+}
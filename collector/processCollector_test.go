@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplicationRestarts(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	process := func(pid int32, name string, created time.Duration) processSnapshot {
+		return processSnapshot{Pid: pid, Name: name, CreateTime: start.Add(created)}
+	}
+	scan := time.Now()
+	tests := []struct {
+		name         string
+		previous     []processSnapshot
+		current      []processSnapshot
+		wantRestarts int
+		wantStarts   int
+		wantExits    int
+	}{
+		{
+			name:         "helper churn next to a running main process",
+			previous:     []processSnapshot{process(1, "ms-teams.exe", 0), process(2, "ms-teams.exe", time.Minute)},
+			current:      []processSnapshot{process(1, "ms-teams.exe", 0), process(3, "ms-teams.exe", 58*time.Minute)},
+			wantRestarts: 0, wantStarts: 1, wantExits: 1,
+		},
+		{
+			name:         "every instance replaced",
+			previous:     []processSnapshot{process(1, "ms-teams.exe", 0), process(2, "ms-teams.exe", time.Minute)},
+			current:      []processSnapshot{process(4, "ms-teams.exe", 58*time.Minute), process(5, "ms-teams.exe", 59*time.Minute)},
+			wantRestarts: 1, wantStarts: 2, wantExits: 2,
+		},
+		{
+			name:         "exit without replacement",
+			previous:     []processSnapshot{process(1, "ms-teams.exe", 0)},
+			current:      []processSnapshot{},
+			wantRestarts: 0, wantStarts: 0, wantExits: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			collector := NewProcessCollector(ProcessConfig{CrashLoopWindow: time.Hour}, nil)
+			collector.prevProcesses = make(map[int32]processSnapshot)
+			collector.prevApplications = make(map[int32]string)
+			collector.processes = make(map[int32]processSnapshot)
+			collector.applications = make(map[int32]string)
+			for _, snapshot := range test.previous {
+				collector.prevProcesses[snapshot.Pid] = snapshot
+				collector.prevApplications[snapshot.Pid] = "Teams"
+			}
+			for _, snapshot := range test.current {
+				collector.processes[snapshot.Pid] = snapshot
+				collector.applications[snapshot.Pid] = "Teams"
+			}
+			collector.prevCollectTime = scan.Add(-5 * time.Minute)
+			collector.collectTime = scan
+			collector.detectLifecycle()
+
+			if got := collector.applicationRestarts["Teams"]; got != test.wantRestarts {
+				t.Errorf("restarts = %d, want %d", got, test.wantRestarts)
+			}
+			if got := collector.applicationStarts["Teams"]; got != test.wantStarts {
+				t.Errorf("starts = %d, want %d", got, test.wantStarts)
+			}
+			if got := collector.applicationExits["Teams"]; got != test.wantExits {
+				t.Errorf("exits = %d, want %d", got, test.wantExits)
+			}
+		})
+	}
+}
+
+func TestTopApplicationsAreDebouncedAndPruned(t *testing.T) {
+	collector := NewProcessCollector(ProcessConfig{CollectUserMetrics: true}, nil)
+	scan := func(processes ...processSnapshot) []Event {
+		collector.processes = make(map[int32]processSnapshot)
+		collector.applications = make(map[int32]string)
+		for _, snapshot := range processes {
+			collector.processes[snapshot.Pid] = snapshot
+		}
+		collector.detectTopApplications()
+		return collector.GetEvents()
+	}
+	excel := processSnapshot{Pid: 1, Name: "excel.exe", Username: "alice", CPUPercent: 30}
+	teams := processSnapshot{Pid: 2, Name: "ms-teams.exe", Username: "bob", CPUPercent: 10}
+
+	for index := range topApplicationScans {
+		events := scan(excel, teams)
+		if want := index == topApplicationScans-1; (len(events) == 2) != want {
+			t.Fatalf("scan %d raised %d events", index+1, len(events))
+		}
+	}
+	if got := (*collector.GetUserMetrics())["alice"]["User Top Application CPU %"]; got != 30 {
+		t.Errorf("top application CPU = %v, want 30", got)
+	}
+	if events := scan(excel, teams); len(events) != 0 {
+		t.Errorf("unchanged top applications raised %v", events)
+	}
+
+	scan(excel)
+	if _, ok := collector.topApplications["bob"]; ok {
+		t.Error("a user without processes is still tracked")
+	}
+}
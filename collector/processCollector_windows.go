@@ -0,0 +1,76 @@
+//go:build windows
+
+package collector
+
+import (
+	"errors"
+	"if-win-dex-agent/internal/headers/iphlpapi"
+	"if-win-dex-agent/internal/headers/psapi"
+
+	"github.com/shirou/gopsutil/v4/process"
+	"golang.org/x/sys/windows"
+)
+
+// iphlpapiSocketLister reads the sockets from the extended TCP tables, which
+// carry the owning PID of every row.
+type iphlpapiSocketLister struct{}
+
+func newSocketLister() socketLister {
+	return &iphlpapiSocketLister{}
+}
+
+func (lister *iphlpapiSocketLister) ListSockets() ([]processSocket, error) {
+	var sockets []processSocket
+	var errs []error
+	for _, family := range []uint32{windows.AF_INET, windows.AF_INET6} {
+		connections, err := iphlpapi.GetTCPConnections(family)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, connection := range connections {
+			socket := processSocket{
+				Pid:       int32(connection.OwningPID),
+				State:     normalizeTCPState(connection.State),
+				LocalPort: connection.LocalPort,
+			}
+			if connection.RemoteAddr.IsValid() && !connection.RemoteAddr.IsUnspecified() {
+				socket.RemoteAddr = connection.RemoteAddr.String()
+			}
+			sockets = append(sockets, socket)
+		}
+	}
+	return sockets, errors.Join(errs...)
+}
+
+func (lister *iphlpapiSocketLister) ListeningPortOwners(ports []uint16) (map[uint16]int32, error) {
+	result := make(map[uint16]int32)
+	for _, port := range ports {
+		// The lookup fails when nobody listens on the port.
+		pid, err := iphlpapi.GetOwnerPIDOfTCPPort(windows.AF_INET, port)
+		if err != nil {
+			pid, err = iphlpapi.GetOwnerPIDOfTCPPort(windows.AF_INET6, port)
+		}
+		if err == nil {
+			result[port] = int32(pid)
+		}
+	}
+	return result, nil
+}
+
+// readPageFaults fills the page fault count from GetProcessMemoryInfo, since
+// gopsutil does not implement page faults on Windows. Windows counts soft and
+// hard faults together, so there is no minor/major split.
+func readPageFaults(p *process.Process, snapshot *processSnapshot) {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(p.Pid))
+	if err != nil {
+		return
+	}
+	defer windows.CloseHandle(handle)
+	counters, err := psapi.GetProcessMemoryInfo(handle)
+	if err != nil {
+		return
+	}
+	snapshot.HasPageFaults = true
+	snapshot.PageFaults = uint64(counters.PageFaultCount)
+}
@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// attachSockets fills in the listening ports, established connections and
+// distinct remote addresses of every process. Sockets without an owner are
+// skipped.
+func attachSockets(snapshots map[int32]processSnapshot, sockets []processSocket) {
+	remoteAddrs := make(map[int32]map[string]bool)
+	for _, socket := range sockets {
+		snapshot, ok := snapshots[socket.Pid]
+		if !ok || socket.Pid == 0 {
+			continue
+		}
+		switch socket.State {
+		case TCPStateListen:
+			if !slices.Contains(snapshot.ListeningPorts, socket.LocalPort) {
+				snapshot.ListeningPorts = append(snapshot.ListeningPorts, socket.LocalPort)
+			}
+		case TCPStateEstablished:
+			snapshot.EstablishedConnections++
+			if socket.RemoteAddr != "" {
+				if remoteAddrs[socket.Pid] == nil {
+					remoteAddrs[socket.Pid] = make(map[string]bool)
+				}
+				remoteAddrs[socket.Pid][socket.RemoteAddr] = true
+			}
+		}
+		snapshots[socket.Pid] = snapshot
+	}
+	for pid, snapshot := range snapshots {
+		slices.Sort(snapshot.ListeningPorts)
+		snapshot.RemoteEndpoints = len(remoteAddrs[pid])
+		snapshots[pid] = snapshot
+	}
+}
+
+// detectListeningPortChanges reports processes that opened or closed
+// listening ports since the previous scan. Nothing is reported for the very
+// first scan.
+func (collector *ProcessCollector) detectListeningPortChanges() {
+	if collector.prevProcesses == nil {
+		return
+	}
+	for pid, snapshot := range collector.processes {
+		var previousPorts []uint16
+		if previous, existed := collector.prevProcesses[pid]; existed && isSameProcess(previous, snapshot) {
+			previousPorts = previous.ListeningPorts
+		}
+		if slices.Equal(previousPorts, snapshot.ListeningPorts) {
+			continue
+		}
+		collector.events = append(collector.events, Event{
+			Timestamp: collector.collectTime,
+			Device:    snapshot.Name,
+			Type:      EventListeningPorts,
+			Message:   fmt.Sprintf("%s (PID %d) listens on ports %v", snapshot.Name, pid, snapshot.ListeningPorts),
+			Data: map[string]interface{}{
+				"pid":           pid,
+				"name":          snapshot.Name,
+				"ports":         snapshot.ListeningPorts,
+				"previousPorts": previousPorts,
+			},
+		})
+	}
+}
+
+// checkPortOwners raises a port conflict when a configured port is owned by
+// an executable that is not expected on it. A conflict is reported once per
+// offending process.
+func (collector *ProcessCollector) checkPortOwners() {
+	if len(collector.config.PortOwners) == 0 {
+		return
+	}
+	ports := make([]uint16, 0, len(collector.config.PortOwners))
+	for port := range collector.config.PortOwners {
+		ports = append(ports, port)
+	}
+	owners, err := collector.sockets.ListeningPortOwners(ports)
+	if err != nil {
+		slog.Error("Error getting listening port owners", "error", err)
+		return
+	}
+
+	for _, port := range ports {
+		pid, ok := owners[port]
+		if !ok {
+			delete(collector.portConflicts, port)
+			continue
+		}
+		expected := collector.config.PortOwners[port]
+		name := collector.processes[pid].Name
+		if slices.ContainsFunc(expected, func(exe string) bool { return strings.EqualFold(exe, name) }) {
+			delete(collector.portConflicts, port)
+			continue
+		}
+		if conflict, reported := collector.portConflicts[port]; reported && conflict == pid {
+			continue
+		}
+		collector.portConflicts[port] = pid
+		if name == "" {
+			name = fmt.Sprintf("PID %d", pid)
+		}
+		collector.events = append(collector.events, Event{
+			Timestamp: collector.collectTime,
+			Device:    name,
+			Type:      EventPortConflict,
+			Message:   fmt.Sprintf("Port %d is owned by %s (PID %d), expected %s", port, name, pid, strings.Join(expected, ", ")),
+			Data: map[string]interface{}{
+				"port":     port,
+				"pid":      pid,
+				"name":     name,
+				"expected": expected,
+			},
+		})
+	}
+}
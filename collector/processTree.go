@@ -0,0 +1,138 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// parentOf returns the parent of a process when it is still running and was
+// created before the child, which rules out a parent PID that has been reused.
+func (collector *ProcessCollector) parentOf(snapshot processSnapshot) (processSnapshot, bool) {
+	if snapshot.Ppid == snapshot.Pid {
+		return processSnapshot{}, false
+	}
+	parent, ok := collector.processes[snapshot.Ppid]
+	if !ok {
+		return processSnapshot{}, false
+	}
+	if !parent.CreateTime.IsZero() && !snapshot.CreateTime.IsZero() && parent.CreateTime.After(snapshot.CreateTime) {
+		return processSnapshot{}, false
+	}
+	return parent, true
+}
+
+// treeGroup returns the process a snapshot is rolled up into. In root mode that
+// is the topmost ancestor with the same executable name, so every browser or
+// Electron helper lands on its main process. In ancestor mode it is the
+// nearest process, itself included, named in the configured ancestor list.
+func (collector *ProcessCollector) treeGroup(snapshot processSnapshot) (processSnapshot, bool) {
+	visited := map[int32]bool{snapshot.Pid: true}
+	current := snapshot
+	for {
+		if collector.config.ProcessTreeMode == ProcessTreeAncestor && collector.isTreeAncestor(current.Name) {
+			return current, true
+		}
+		parent, ok := collector.parentOf(current)
+		if !ok || visited[parent.Pid] {
+			break
+		}
+		if collector.config.ProcessTreeMode == ProcessTreeRoot && !strings.EqualFold(parent.Name, current.Name) {
+			break
+		}
+		visited[parent.Pid] = true
+		current = parent
+	}
+	return current, collector.config.ProcessTreeMode == ProcessTreeRoot
+}
+
+func (collector *ProcessCollector) isTreeAncestor(name string) bool {
+	for _, ancestor := range collector.config.ProcessTreeAncestors {
+		if strings.EqualFold(ancestor, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProcessTreeMetrics reports cumulative usage per tree. Trees rooted at the
+// same executable are combined under "<name> Tree", like the per-process
+// metrics are keyed by name.
+func (collector *ProcessCollector) GetProcessTreeMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	if collector.config.ProcessTreeMode == ProcessTreeOff || collector.config.ProcessTreeMode == "" {
+		return &result
+	}
+
+	roots := make(map[string]map[int32]bool)
+	for _, snapshot := range collector.processes {
+		group, ok := collector.treeGroup(snapshot)
+		if !ok {
+			continue
+		}
+		device := group.Name + " Tree"
+		if _, exists := result[device]; !exists {
+			result[device] = map[string]float64{
+				"Process Tree CPU Usage %":    0,
+				"Process Tree Memory Used MB": 0,
+				"Process Tree Process Count":  0,
+			}
+			roots[device] = make(map[int32]bool)
+		}
+		result[device]["Process Tree CPU Usage %"] += snapshot.CPUPercent
+		result[device]["Process Tree Memory Used MB"] += float64(snapshot.RSS) / 1024 / 1024
+		result[device]["Process Tree Process Count"]++
+		roots[device][group.Pid] = true
+	}
+	for device, pids := range roots {
+		result[device]["Process Tree Root Count"] = float64(len(pids))
+	}
+	return &result
+}
+
+// FormatProcessTree renders the last scan as an indented parent/child tree with
+// the cumulative CPU and memory of every subtree, for troubleshooting.
+func (collector *ProcessCollector) FormatProcessTree() string {
+	children := make(map[int32][]int32)
+	roots := make([]int32, 0)
+	for pid, snapshot := range collector.processes {
+		if parent, ok := collector.parentOf(snapshot); ok {
+			children[parent.Pid] = append(children[parent.Pid], pid)
+		} else {
+			roots = append(roots, pid)
+		}
+	}
+	slices.Sort(roots)
+	for pid := range children {
+		slices.Sort(children[pid])
+	}
+
+	var totals func(pid int32) (float64, uint64)
+	totals = func(pid int32) (float64, uint64) {
+		cpuPercent, rss := collector.processes[pid].CPUPercent, collector.processes[pid].RSS
+		for _, child := range children[pid] {
+			childCPU, childRSS := totals(child)
+			cpuPercent += childCPU
+			rss += childRSS
+		}
+		return cpuPercent, rss
+	}
+
+	var builder strings.Builder
+	var write func(pid int32, depth int)
+	write = func(pid int32, depth int) {
+		snapshot := collector.processes[pid]
+		cpuPercent, rss := totals(pid)
+		fmt.Fprintf(&builder, "%s%s (%d) cpu=%.1f%% mem=%.1fMB tree_cpu=%.1f%% tree_mem=%.1fMB\n",
+			strings.Repeat("  ", depth), snapshot.Name, pid,
+			snapshot.CPUPercent, float64(snapshot.RSS)/1024/1024,
+			cpuPercent, float64(rss)/1024/1024)
+		for _, child := range children[pid] {
+			write(child, depth+1)
+		}
+	}
+	for _, pid := range roots {
+		write(pid, 0)
+	}
+	return builder.String()
+}
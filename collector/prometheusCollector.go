@@ -0,0 +1,306 @@
+package collector
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+// prometheusMaxBytes caps what is read from one file or URL. Longer text is
+// cut after its last complete line.
+const prometheusMaxBytes = 16 * 1024 * 1024
+
+type PrometheusCollector struct {
+	config   PrometheusConfig
+	metrics  map[string]map[string]float64
+	sources  map[string]prometheusSourceResult
+	counters map[string]prometheusCounter
+}
+
+func NewPrometheusCollector(config PrometheusConfig) *PrometheusCollector {
+	return &PrometheusCollector{
+		config:   config,
+		metrics:  make(map[string]map[string]float64),
+		sources:  make(map[string]prometheusSourceResult),
+		counters: make(map[string]prometheusCounter),
+	}
+}
+
+// LoadPrometheusConfig reads the sources from [prometheus] and the mapping
+// rules from [prometheus_rule:<name>] sections, for example:
+//
+//	[prometheus]
+//	textfile_directory = C:\ProgramData\win-dex-agent\textfile
+//	scrape_urls = http://localhost:9182/metrics
+//	timeout = 10s
+//	counters_as_rates = true
+//	max_series = 1000
+//
+//	[prometheus_rule:Disks]
+//	priority = 10
+//	metric_pattern = ^windows_logical_disk_
+//	instance_labels = volume
+//	metric_labels = mode
+//	metric_name = ${name}
+//	drop = false
+func LoadPrometheusConfig(p *configparser.ConfigParser) PrometheusConfig {
+	config := PrometheusConfig{
+		TextfileDirectory: getConfigString(p, PrometheusSectionName, "textfile_directory", ""),
+		ScrapeURLs:        getConfigList(p, PrometheusSectionName, "scrape_urls"),
+		Timeout:           getConfigDuration(p, PrometheusSectionName, "timeout", 10*time.Second),
+		CountersAsRates:   getConfigBool(p, PrometheusSectionName, "counters_as_rates", true),
+		MaxSeries:         getConfigInt(p, PrometheusSectionName, "max_series", 1000),
+	}
+	for _, section := range getSectionsWithPrefix(p, PrometheusRuleSectionPrefix) {
+		rule := PrometheusRule{
+			Name:           strings.TrimSpace(strings.TrimPrefix(section, PrometheusRuleSectionPrefix)),
+			Priority:       getConfigInt(p, section, "priority", 0),
+			InstanceLabels: getConfigList(p, section, "instance_labels"),
+			MetricLabels:   getConfigList(p, section, "metric_labels"),
+			MetricName:     getConfigString(p, section, "metric_name", "${name}"),
+			Drop:           getConfigBool(p, section, "drop", false),
+		}
+		pattern, err := regexp.Compile(getConfigString(p, section, "metric_pattern", ".*"))
+		if err != nil {
+			slog.Error("Invalid metric_pattern for Prometheus rule, skipping it", "rule", rule.Name, "error", err)
+			continue
+		}
+		rule.MetricPattern = pattern
+		config.Rules = append(config.Rules, rule)
+	}
+	// The sections come sorted by name, which orders rules of equal priority.
+	slices.SortStableFunc(config.Rules, func(a, b PrometheusRule) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+	return config
+}
+
+// Collect reads every text file and scrapes every URL. The URLs are scraped
+// concurrently, so a slow endpoint does not delay the others beyond its own
+// timeout.
+func (collector *PrometheusCollector) Collect() {
+	type sourceOutput struct {
+		text   string
+		result prometheusSourceResult
+	}
+	outputs := make(map[string]*sourceOutput)
+
+	if collector.config.TextfileDirectory != "" {
+		files, err := filepath.Glob(filepath.Join(collector.config.TextfileDirectory, "*.prom"))
+		if err != nil {
+			slog.Error("Failed to list Prometheus text files", "directory", collector.config.TextfileDirectory, "error", err)
+		}
+		for _, file := range files {
+			start := time.Now()
+			text, truncated, err := readPrometheusFile(file)
+			if err != nil {
+				slog.Warn("Failed to read Prometheus text file", "file", file, "error", err)
+			} else if truncated {
+				slog.Warn("Prometheus text file was truncated", "file", file, "limit", prometheusMaxBytes)
+			}
+			outputs[filepath.Base(file)] = &sourceOutput{text: text, result: prometheusSourceResult{Success: err == nil, Duration: time.Since(start)}}
+		}
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, url := range collector.config.ScrapeURLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			text, truncated, err := scrapePrometheus(url, collector.config.Timeout)
+			if err != nil {
+				slog.Warn("Failed to scrape Prometheus metrics", "url", url, "error", err)
+			} else if truncated {
+				slog.Warn("Prometheus scrape was truncated", "url", url, "limit", prometheusMaxBytes)
+			}
+			mutex.Lock()
+			outputs[url] = &sourceOutput{text: text, result: prometheusSourceResult{Success: err == nil, Duration: time.Since(start)}}
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	now := time.Now()
+	metrics := make(map[string]map[string]float64)
+	sources := make(map[string]prometheusSourceResult)
+	counters := make(map[string]prometheusCounter)
+	for source, output := range outputs {
+		samples, err := parsePrometheusText(output.text)
+		if err != nil {
+			slog.Warn("Skipped malformed Prometheus lines", "source", source, "error", err)
+		}
+		output.result.SeriesCount = collector.addSamples(source, samples, now, metrics, counters)
+		sources[source] = output.result
+		if !output.result.Success {
+			// Keep the baselines of a source that could not be read, so its
+			// rates resume with the next successful read.
+			for key, counter := range collector.counters {
+				if _, ok := counters[key]; !ok && strings.HasPrefix(key, source+"|") {
+					counters[key] = counter
+				}
+			}
+		}
+	}
+	collector.metrics = metrics
+	collector.sources = sources
+	collector.counters = counters
+}
+
+func readPrometheusFile(file string) (string, bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	return readPrometheusText(f)
+}
+
+func scrapePrometheus(url string, timeout time.Duration) (string, bool, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", false, err
+	}
+	request.Header.Set("Accept", "text/plain;version=0.0.4")
+	client := &http.Client{Timeout: timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return "", false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return readPrometheusText(response.Body)
+}
+
+// readPrometheusText reads at most prometheusMaxBytes and reports whether the
+// text was longer. The last line of a truncated text is cut off and could hold
+// a wrong value, so it is dropped.
+func readPrometheusText(reader io.Reader) (string, bool, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, prometheusMaxBytes+1))
+	if err != nil || len(data) <= prometheusMaxBytes {
+		return string(data), false, err
+	}
+	data = data[:prometheusMaxBytes]
+	return string(data[:bytes.LastIndexByte(data, '\n')+1]), true, nil
+}
+
+// addSamples maps the samples of a source into metrics and returns the number
+// of series taken. Samples without a device are reported under the source
+// name, next to its scrape status. Histogram buckets are left out; counters, and the sums and
+// counts of histograms and summaries, become rates when configured, starting
+// from the second collection a series is seen in.
+func (collector *PrometheusCollector) addSamples(source string, samples []metricSample, now time.Time, metrics map[string]map[string]float64, counters map[string]prometheusCounter) int {
+	seriesCount := 0
+	for _, sample := range samples {
+		if isHistogramBucket(sample) {
+			continue
+		}
+		device, metric, ok := collector.mapSample(sample)
+		if !ok {
+			continue
+		}
+		if device == "" {
+			device = source
+		}
+		if seriesCount >= collector.config.MaxSeries {
+			slog.Warn("Too many Prometheus series, ignoring the rest", "source", source, "maxSeries", collector.config.MaxSeries)
+			break
+		}
+		seriesCount++
+
+		value := sample.Value
+		if collector.config.CountersAsRates && isCumulativeSample(sample) {
+			key := source + "|" + device + "|" + metric
+			previous, seen := collector.counters[key]
+			counters[key] = prometheusCounter{Value: sample.Value, Timestamp: now}
+			seconds := now.Sub(previous.Timestamp).Seconds()
+			if !seen || sample.Value < previous.Value || seconds <= 0 {
+				continue
+			}
+			value = (sample.Value - previous.Value) / seconds
+			metric += "/s"
+		}
+		if _, exists := metrics[device]; !exists {
+			metrics[device] = make(map[string]float64)
+		}
+		metrics[device][metric] = value
+	}
+	return seriesCount
+}
+
+func isCumulativeSample(sample metricSample) bool {
+	switch sample.Type {
+	case PrometheusCounter:
+		return true
+	case PrometheusHistogram, PrometheusSummary:
+		return strings.HasSuffix(sample.Name, "_sum") || strings.HasSuffix(sample.Name, "_count")
+	}
+	return false
+}
+
+// mapSample returns the device and metric name of a sample through the first
+// matching rule. Without one, the default mapping shared with the exec
+// collector applies. The device is empty when no label provides one.
+func (collector *PrometheusCollector) mapSample(sample metricSample) (string, string, bool) {
+	for _, rule := range collector.config.Rules {
+		if !rule.MetricPattern.MatchString(sample.Name) {
+			continue
+		}
+		if rule.Drop {
+			return "", "", false
+		}
+		values := make([]string, 0, len(rule.InstanceLabels))
+		for _, label := range rule.InstanceLabels {
+			if value := sample.Labels[label]; value != "" {
+				values = append(values, value)
+			}
+		}
+		metric := transactionVariablePattern.ReplaceAllStringFunc(rule.MetricName, func(reference string) string {
+			name := reference[2 : len(reference)-1]
+			if name == "name" {
+				return sample.Name
+			}
+			return sample.Labels[name]
+		})
+		return strings.Join(values, " "), appendMetricLabels(metric, sample.Labels, rule.MetricLabels), true
+	}
+
+	device, metric := defaultPrometheusMapping(sample)
+	return device, metric, true
+}
+
+// GetPrometheusMetrics reports the mapped series of the last collection and
+// whether each file or URL could be read, under its file name or URL.
+func (collector *PrometheusCollector) GetPrometheusMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for device, metrics := range collector.metrics {
+		result[device] = make(map[string]float64, len(metrics))
+		for metric, value := range metrics {
+			result[device][metric] = value
+		}
+	}
+	for source, sourceResult := range collector.sources {
+		if _, ok := result[source]; !ok {
+			result[source] = make(map[string]float64)
+		}
+		result[source]["Prometheus Scrape Success"] = boolMetric(sourceResult.Success)
+		result[source]["Prometheus Scrape Duration ms"] = float64(sourceResult.Duration.Milliseconds())
+		result[source]["Prometheus Series Count"] = float64(sourceResult.SeriesCount)
+	}
+	return &result
+}
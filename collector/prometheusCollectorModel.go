@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"regexp"
+	"time"
+)
+
+const PrometheusSectionName = "prometheus"
+const PrometheusRuleSectionPrefix = "prometheus_rule:"
+
+// PrometheusRule maps the series whose name matches MetricPattern onto an
+// instance and a metric name. The values of InstanceLabels form the device,
+// or the source name when the sample has none of them;
+// MetricName may refer to ${name} and to labels, and MetricLabels are added
+// to it as label=value. Other labels are ignored, so series differing only in
+// them overwrite each other.
+type PrometheusRule struct {
+	Name           string
+	Priority       int
+	MetricPattern  *regexp.Regexp
+	InstanceLabels []string
+	MetricLabels   []string
+	MetricName     string
+	Drop           bool
+}
+
+type PrometheusConfig struct {
+	// TextfileDirectory is searched for *.prom files on every collection.
+	TextfileDirectory string
+	ScrapeURLs        []string
+	Timeout           time.Duration
+	// CountersAsRates reports counters as per-second rates between collections.
+	CountersAsRates bool
+	// MaxSeries caps the series taken from one file or URL.
+	MaxSeries int
+	// Rules are tried by ascending priority, then by name, and the first
+	// matching one applies.
+	Rules []PrometheusRule
+}
+
+// prometheusSourceResult is the outcome of reading one file or URL.
+type prometheusSourceResult struct {
+	Success     bool
+	Duration    time.Duration
+	SeriesCount int
+}
+
+type prometheusCounter struct {
+	Value     float64
+	Timestamp time.Time
+}
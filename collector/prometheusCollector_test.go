@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+func TestLoadPrometheusConfigOrdersRulesByPriority(t *testing.T) {
+	p, err := configparser.ParseReader(strings.NewReader(`
+[prometheus_rule:A]
+metric_pattern = ^a_
+[prometheus_rule:B]
+priority = -1
+[prometheus_rule:C]
+priority = -1
+[prometheus_rule:D]
+priority = 5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, rule := range LoadPrometheusConfig(p).Rules {
+		names = append(names, rule.Name)
+	}
+	if want := []string{"B", "C", "A", "D"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("rule order = %v, want %v", names, want)
+	}
+}
+
+func TestPrometheusCollectorFile(t *testing.T) {
+	directory := t.TempDir()
+	text := `# TYPE windows_logical_disk_free_bytes gauge
+windows_logical_disk_free_bytes{volume="C:"} 1000
+windows_logical_disk_free_bytes{volume="D:"} 2000
+# TYPE process_open_fds gauge
+process_open_fds 12
+# TYPE queue_depth gauge
+queue_depth{queue="inbound"} 3
+`
+	if err := os.WriteFile(filepath.Join(directory, "app.prom"), []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := PrometheusConfig{
+		TextfileDirectory: directory,
+		MaxSeries:         100,
+		Rules: []PrometheusRule{
+			{Name: "Free", MetricPattern: regexp.MustCompile("^windows_logical_disk_free_bytes$"), InstanceLabels: []string{"volume"}, MetricName: "Free Bytes"},
+			{Name: "Disks", MetricPattern: regexp.MustCompile("^windows_logical_disk_"), Drop: true},
+			{Name: "Queues", MetricPattern: regexp.MustCompile("^queue_"), InstanceLabels: []string{"missing"}, MetricName: "${name} ${queue}"},
+		},
+	}
+	collector := NewPrometheusCollector(config)
+	collector.Collect()
+	metrics := *collector.GetPrometheusMetrics()
+
+	want := map[string]map[string]float64{
+		"C:": {"Free Bytes": 1000},
+		"D:": {"Free Bytes": 2000},
+		"app.prom": {
+			"process_open_fds":              12,
+			"queue_depth inbound":           3,
+			"Prometheus Scrape Success":     1,
+			"Prometheus Series Count":       4,
+			"Prometheus Scrape Duration ms": metrics["app.prom"]["Prometheus Scrape Duration ms"],
+		},
+	}
+	if !reflect.DeepEqual(metrics, want) {
+		t.Errorf("metrics = %v, want %v", metrics, want)
+	}
+}
+
+func TestPrometheusCollectorKeepsBaselinesOfFailedScrapes(t *testing.T) {
+	status, total := http.StatusOK, 100
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "# TYPE requests_total counter\nrequests_total %d\n", total)
+	}))
+	defer server.Close()
+	collector := NewPrometheusCollector(PrometheusConfig{
+		ScrapeURLs:      []string{server.URL},
+		Timeout:         5 * time.Second,
+		MaxSeries:       100,
+		CountersAsRates: true,
+	})
+
+	collector.Collect()
+	status = http.StatusInternalServerError
+	time.Sleep(10 * time.Millisecond)
+	collector.Collect()
+	if metrics := *collector.GetPrometheusMetrics(); metrics[server.URL]["Prometheus Scrape Success"] != 0 {
+		t.Fatalf("scrape with status 500 reported as successful: %v", metrics)
+	}
+	status, total = http.StatusOK, 160
+	time.Sleep(10 * time.Millisecond)
+	collector.Collect()
+	rate, ok := (*collector.GetPrometheusMetrics())[server.URL]["requests_total/s"]
+	if !ok || rate <= 0 {
+		t.Errorf("requests_total/s = %v (reported %v), want a positive rate against the baseline from before the failure", rate, ok)
+	}
+}
+
+func TestReadPrometheusTextDropsTheCutLine(t *testing.T) {
+	line := "metric_with_a_long_name 1234567890\n"
+	text := strings.Repeat(line, prometheusMaxBytes/len(line)+1)
+	got, truncated, err := readPrometheusText(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !truncated {
+		t.Error("expected the text to be truncated")
+	}
+	if len(got) > prometheusMaxBytes || !strings.HasSuffix(got, line) {
+		t.Errorf("truncated text has %d bytes and ends with %q", len(got), got[max(0, len(got)-len(line)):])
+	}
+
+	got, truncated, err = readPrometheusText(strings.NewReader(line))
+	if err != nil || truncated || got != line {
+		t.Errorf("readPrometheusText(short) = %q, %v, %v", got, truncated, err)
+	}
+}
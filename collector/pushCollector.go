@@ -0,0 +1,313 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+// pushMaxTimerSamples caps the values kept per timer and interval; count,
+// sum, min and max still include every value and the kept ones are sampled
+// from all of them.
+const pushMaxTimerSamples = 10000
+
+// pushMaxBodyBytes caps the body of a request to the HTTP API.
+const pushMaxBodyBytes = 1024 * 1024
+
+type PushCollector struct {
+	config  PushConfig
+	mutex   sync.Mutex
+	series  map[pushSeriesKey]*pushSeries
+	metrics map[string]map[string]float64
+}
+
+func NewPushCollector(config PushConfig) *PushCollector {
+	return &PushCollector{
+		config:  config,
+		series:  make(map[pushSeriesKey]*pushSeries),
+		metrics: make(map[string]map[string]float64),
+	}
+}
+
+// LoadPushConfig reads the listeners from [push], for example:
+//
+//	[push]
+//	http_address = 127.0.0.1:9125
+//	statsd_address = 127.0.0.1:8125
+//	percentiles = 50, 90, 99
+//	max_series = 1000
+//	idle_intervals = 12
+func LoadPushConfig(p *configparser.ConfigParser) PushConfig {
+	config := PushConfig{
+		HTTPAddress:   getConfigString(p, PushSectionName, "http_address", ""),
+		StatsDAddress: getConfigString(p, PushSectionName, "statsd_address", ""),
+		MaxSeries:     getConfigInt(p, PushSectionName, "max_series", 1000),
+		IdleIntervals: getConfigInt(p, PushSectionName, "idle_intervals", 12),
+	}
+	percentiles := getConfigList(p, PushSectionName, "percentiles")
+	if len(percentiles) == 0 {
+		percentiles = []string{"50", "90", "99"}
+	}
+	for _, text := range percentiles {
+		percentile, err := strconv.ParseFloat(text, 64)
+		if err != nil || percentile <= 0 || percentile > 100 {
+			slog.Error("Invalid push percentile, skipping it", "percentile", text)
+			continue
+		}
+		config.Percentiles = append(config.Percentiles, percentile)
+	}
+	return config
+}
+
+// Start opens the configured listeners in the background. They only accept
+// data while the agent runs, so they are not started for dry runs. The API
+// has no authentication, so addresses other hosts could reach are refused.
+func (collector *PushCollector) Start() {
+	if address := collector.config.HTTPAddress; address != "" {
+		if err := checkLoopbackAddress(address); err != nil {
+			slog.Error("Not starting the push HTTP API", "address", address, "error", err)
+		} else {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/metrics", collector.handleHTTP)
+			server := &http.Server{
+				Addr:              address,
+				Handler:           mux,
+				ReadHeaderTimeout: 5 * time.Second,
+				ReadTimeout:       10 * time.Second,
+				WriteTimeout:      10 * time.Second,
+				IdleTimeout:       time.Minute,
+			}
+			go func() {
+				slog.Info("Listening for pushed metrics", "address", address)
+				if err := server.ListenAndServe(); err != nil {
+					slog.Error("Push HTTP API stopped", "address", address, "error", err)
+				}
+			}()
+		}
+	}
+	if address := collector.config.StatsDAddress; address != "" {
+		if err := checkLoopbackAddress(address); err != nil {
+			slog.Error("Not starting the StatsD listener", "address", address, "error", err)
+		} else {
+			go collector.listenStatsD(address)
+		}
+	}
+}
+
+// checkLoopbackAddress accepts a host:port whose host is a loopback address
+// or a name resolving only to loopback addresses, such as localhost.
+func checkLoopbackAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return errors.New("the address would listen on every interface")
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return fmt.Errorf("%s is not a loopback address", ip)
+		}
+	}
+	return nil
+}
+
+// handleHTTP accepts a POST of a JSON array of samples, or of an object with
+// the array in "metrics", such as:
+//
+//	[{"device": "CRM", "name": "Login ms", "type": "timer", "value": 820}]
+func (collector *PushCollector) handleHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, pushMaxBodyBytes))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	samples, err := parsePushJSON(body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := collector.add(samples); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func parsePushJSON(body []byte) ([]pushSample, error) {
+	var samples []pushSample
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "{") {
+		var document struct {
+			Metrics []pushSample `json:"metrics"`
+		}
+		if err := json.Unmarshal(body, &document); err != nil {
+			return nil, err
+		}
+		samples = document.Metrics
+	} else if err := json.Unmarshal(body, &samples); err != nil {
+		return nil, err
+	}
+	for i := range samples {
+		if samples[i].Type == "" {
+			samples[i].Type = PushGauge
+		}
+	}
+	return samples, nil
+}
+
+// add aggregates the samples into their series and returns the reasons the
+// rejected ones were not taken.
+func (collector *PushCollector) add(samples []pushSample) error {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	var errs []error
+	for _, sample := range samples {
+		if err := collector.addSample(sample, false); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sample.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// addSample aggregates one sample; delta adds a gauge sample to the current
+// value like the signed StatsD gauges do. The caller holds the mutex.
+func (collector *PushCollector) addSample(sample pushSample, delta bool) error {
+	sample.Name = strings.TrimSpace(sample.Name)
+	if sample.Name == "" {
+		return errors.New("missing metric name")
+	}
+	if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+		return errors.New("non-finite value")
+	}
+	if sample.Type != PushGauge && sample.Type != PushCounter && sample.Type != PushTimer {
+		return fmt.Errorf("unknown type %q", sample.Type)
+	}
+	key := pushSeriesKey{Device: sample.Device, Name: sample.Name}
+	series, ok := collector.series[key]
+	if !ok {
+		if len(collector.series) >= collector.config.MaxSeries {
+			return errors.New("too many series")
+		}
+		series = &pushSeries{Type: sample.Type}
+		collector.series[key] = series
+	}
+	if series.Type != sample.Type {
+		return fmt.Errorf("already pushed as a %s", series.Type)
+	}
+	series.Updated = true
+
+	switch sample.Type {
+	case PushGauge:
+		if delta {
+			series.Value += sample.Value
+		} else {
+			series.Value = sample.Value
+		}
+	case PushCounter:
+		series.Value += sample.Value
+	case PushTimer:
+		if series.Count == 0 || sample.Value < series.Min {
+			series.Min = sample.Value
+		}
+		if series.Count == 0 || sample.Value > series.Max {
+			series.Max = sample.Value
+		}
+		series.Count++
+		series.Sum += sample.Value
+		// Reservoir sampling: once full, the value replaces a kept one with
+		// the probability that keeps every value equally likely to be kept.
+		if len(series.Samples) < pushMaxTimerSamples {
+			series.Samples = append(series.Samples, sample.Value)
+		} else if index := rand.IntN(series.Count); index < pushMaxTimerSamples {
+			series.Samples[index] = sample.Value
+		}
+	}
+	return nil
+}
+
+// Collect closes the interval: counters and timers start over while gauges
+// keep their value. Series without a sample for IdleIntervals collections are
+// dropped, so names that are no longer pushed do not fill up MaxSeries.
+func (collector *PushCollector) Collect() {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	metrics := make(map[string]map[string]float64)
+	for key, series := range collector.series {
+		if series.Updated {
+			series.IdleIntervals = 0
+		} else {
+			series.IdleIntervals++
+		}
+		series.Updated = false
+		if collector.config.IdleIntervals > 0 && series.IdleIntervals >= collector.config.IdleIntervals {
+			delete(collector.series, key)
+			continue
+		}
+		if _, ok := metrics[key.Device]; !ok {
+			metrics[key.Device] = make(map[string]float64)
+		}
+		switch series.Type {
+		case PushGauge:
+			metrics[key.Device][key.Name] = series.Value
+		case PushCounter:
+			metrics[key.Device][key.Name] = series.Value
+			series.Value = 0
+		case PushTimer:
+			metrics[key.Device][key.Name+" Count"] = float64(series.Count)
+			if series.Count > 0 {
+				metrics[key.Device][key.Name+" Mean"] = series.Sum / float64(series.Count)
+				metrics[key.Device][key.Name+" Min"] = series.Min
+				metrics[key.Device][key.Name+" Max"] = series.Max
+				slices.Sort(series.Samples)
+				for _, percentile := range collector.config.Percentiles {
+					name := key.Name + " P" + strconv.FormatFloat(percentile, 'f', -1, 64)
+					metrics[key.Device][name] = nearestRank(series.Samples, percentile)
+				}
+			}
+			*series = pushSeries{Type: PushTimer, IdleIntervals: series.IdleIntervals}
+		}
+	}
+	collector.metrics = metrics
+}
+
+// nearestRank returns the percentile of sorted values by the nearest-rank
+// method, which always picks a value that was pushed.
+func nearestRank(sorted []float64, percentile float64) float64 {
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// GetPushMetrics reports the pushed series aggregated over the last interval.
+// Timers are reported as "<name> Count", "Mean", "Min", "Max" and "P<n>".
+func (collector *PushCollector) GetPushMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for device, metrics := range collector.metrics {
+		result[device] = make(map[string]float64, len(metrics))
+		for metric, value := range metrics {
+			result[device][metric] = value
+		}
+	}
+	return &result
+}
@@ -0,0 +1,57 @@
+package collector
+
+const PushSectionName = "push"
+
+// Types of pushed metrics. Gauges keep their last value, counters are summed
+// over the collection interval and timers are summarized with percentiles.
+const (
+	PushGauge   = "gauge"
+	PushCounter = "counter"
+	PushTimer   = "timer"
+)
+
+type PushConfig struct {
+	// HTTPAddress and StatsDAddress are the listen addresses of the HTTP API
+	// and the StatsD UDP listener; either is off when empty.
+	HTTPAddress   string
+	StatsDAddress string
+	Percentiles   []float64
+	// MaxSeries caps the distinct device and metric pairs accepted.
+	MaxSeries int
+	// IdleIntervals is the number of collections without a sample after which
+	// a series is forgotten; 0 keeps series until the agent restarts.
+	IdleIntervals int
+}
+
+// pushSample is a value submitted through the HTTP API or StatsD. Device
+// follows the metric convention: empty for the host itself.
+type pushSample struct {
+	Device string  `json:"device"`
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Value  float64 `json:"value"`
+}
+
+type pushSeriesKey struct {
+	Device string
+	Name   string
+}
+
+// pushSeries aggregates the samples of a series since the last collection.
+// Series are kept while they are pushed to, like StatsD does, so a counter
+// idle for less than IdleIntervals reports 0.
+type pushSeries struct {
+	Type  string
+	Value float64
+	Count int
+	Sum   float64
+	Min   float64
+	Max   float64
+	// Samples of a timer for the percentiles, a uniform random selection of
+	// at most pushMaxTimerSamples values.
+	Samples []float64
+	// Updated is set when a sample arrives; IdleIntervals counts the
+	// collections since the last one.
+	Updated       bool
+	IdleIntervals int
+}
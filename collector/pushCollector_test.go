@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"testing"
+)
+
+func TestCheckLoopbackAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "127.0.0.1:9125"},
+		{address: "[::1]:9125"},
+		{address: "localhost:9125"},
+		{address: ":9125", wantErr: true},
+		{address: "0.0.0.0:9125", wantErr: true},
+		{address: "192.0.2.10:9125", wantErr: true},
+		{address: "9125", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			if err := checkLoopbackAddress(test.address); (err != nil) != test.wantErr {
+				t.Errorf("checkLoopbackAddress(%q) = %v, want error %v", test.address, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestPushCollectorDropsIdleSeries(t *testing.T) {
+	collector := NewPushCollector(PushConfig{MaxSeries: 1, IdleIntervals: 2})
+	if err := collector.add([]pushSample{{Name: "Queue", Type: PushGauge, Value: 5}}); err != nil {
+		t.Fatal(err)
+	}
+	collector.Collect()
+	collector.Collect()
+	if got := (*collector.GetPushMetrics())[""]["Queue"]; got != 5 {
+		t.Errorf("gauge after one idle interval = %v, want 5", got)
+	}
+	if err := collector.add([]pushSample{{Name: "Other", Type: PushGauge, Value: 1}}); err == nil {
+		t.Error("expected a second series to exceed max_series")
+	}
+
+	collector.Collect()
+	if metrics := *collector.GetPushMetrics(); len(metrics) != 0 {
+		t.Errorf("metrics after two idle intervals = %v, want none", metrics)
+	}
+	if err := collector.add([]pushSample{{Name: "Other", Type: PushGauge, Value: 1}}); err != nil {
+		t.Errorf("series still refused after the idle one was dropped: %v", err)
+	}
+}
+
+func TestPushTimerSamplesTheWholeInterval(t *testing.T) {
+	collector := NewPushCollector(PushConfig{MaxSeries: 1, Percentiles: []float64{50}})
+	samples := make([]pushSample, 0, 4*pushMaxTimerSamples)
+	for index := range 4 * pushMaxTimerSamples {
+		// The first quarter of the values are 1, the rest 100.
+		value := 100.0
+		if index < pushMaxTimerSamples {
+			value = 1
+		}
+		samples = append(samples, pushSample{Name: "Login ms", Type: PushTimer, Value: value})
+	}
+	if err := collector.add(samples); err != nil {
+		t.Fatal(err)
+	}
+	collector.Collect()
+	metrics := (*collector.GetPushMetrics())[""]
+	if metrics["Login ms Count"] != float64(len(samples)) || metrics["Login ms Min"] != 1 || metrics["Login ms Max"] != 100 {
+		t.Errorf("timer summary = %v", metrics)
+	}
+	if median := metrics["Login ms P50"]; median != 100 {
+		t.Errorf("median = %v, want 100 from values sampled over the whole interval", median)
+	}
+}
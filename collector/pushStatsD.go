@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// listenStatsD reads StatsD datagrams until the socket fails.
+func (collector *PushCollector) listenStatsD(address string) {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		slog.Error("Failed to open the StatsD listener", "address", address, "error", err)
+		return
+	}
+	defer conn.Close()
+	slog.Info("Listening for StatsD metrics", "address", address)
+	buffer := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			slog.Error("StatsD listener stopped", "address", address, "error", err)
+			return
+		}
+		collector.addStatsD(string(buffer[:n]))
+	}
+}
+
+// addStatsD aggregates the lines of a datagram. Invalid lines are logged and
+// skipped, since UDP senders get no reply.
+func (collector *PushCollector) addStatsD(datagram string) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	for _, line := range strings.Split(datagram, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		sample, delta, err := parseStatsDLine(line)
+		if err == nil {
+			err = collector.addSample(sample, delta)
+		}
+		if err != nil {
+			slog.Debug("Skipped StatsD line", "line", line, "error", err)
+		}
+	}
+}
+
+// parseStatsDLine parses `name:value|type[|@rate][|#tags]`. Types g, c, ms
+// and h are supported, h being taken as a timer. A gauge value with a sign is
+// a delta and counters are scaled up by their sample rate. The device comes
+// from a device tag, as in `login:820|ms|#device:CRM`.
+func parseStatsDLine(line string) (pushSample, bool, error) {
+	sample := pushSample{}
+	colon := strings.LastIndex(strings.SplitN(line, "|", 2)[0], ":")
+	if colon <= 0 {
+		return sample, false, errors.New("missing name or value")
+	}
+	sample.Name = line[:colon]
+	fields := strings.Split(line[colon+1:], "|")
+	if len(fields) < 2 {
+		return sample, false, errors.New("missing type")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, false, fmt.Errorf("invalid value %q", fields[0])
+	}
+	sample.Value = value
+
+	delta := false
+	switch fields[1] {
+	case "g":
+		sample.Type = PushGauge
+		delta = strings.HasPrefix(fields[0], "+") || strings.HasPrefix(fields[0], "-")
+	case "c":
+		sample.Type = PushCounter
+	case "ms", "h":
+		sample.Type = PushTimer
+	default:
+		return sample, false, fmt.Errorf("unsupported type %q", fields[1])
+	}
+
+	for _, field := range fields[2:] {
+		switch {
+		case strings.HasPrefix(field, "@"):
+			rate, err := strconv.ParseFloat(field[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return sample, false, fmt.Errorf("invalid sample rate %q", field)
+			}
+			if sample.Type == PushCounter {
+				sample.Value /= rate
+			}
+		case strings.HasPrefix(field, "#"):
+			for _, tag := range strings.Split(field[1:], ",") {
+				if device, ok := strings.CutPrefix(tag, "device:"); ok {
+					sample.Device = device
+				}
+			}
+		}
+	}
+	return sample, delta, nil
+}
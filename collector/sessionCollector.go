@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+type SessionCollector struct {
+	config   SessionConfig
+	lister   sessionLister
+	sessions []UserSession
+	// collectTime is the reference for session durations and idle times.
+	collectTime time.Time
+}
+
+func NewSessionCollector(config SessionConfig) *SessionCollector {
+	return &SessionCollector{config: config, lister: newSessionLister()}
+}
+
+func LoadSessionConfig(p *configparser.ConfigParser) SessionConfig {
+	return SessionConfig{
+		IdleThreshold: getConfigDuration(p, SessionSectionName, "idle_threshold", 15*time.Minute),
+	}
+}
+
+func (collector *SessionCollector) Collect() {
+	sessions, err := collector.lister.ListSessions()
+	if err != nil {
+		slog.Error("Error listing user sessions", "error", err)
+	}
+	collector.sessions = sessions
+	collector.collectTime = time.Now()
+}
+
+func (collector *SessionCollector) GetSessionMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	result[""] = summarizeSessions(collector.sessions, collector.collectTime, collector.config.IdleThreshold)
+	return &result
+}
+
+// summarizeSessions maps a list of sessions to the session metrics. Active
+// sessions whose last input is older than idleThreshold count as idle.
+func summarizeSessions(sessions []UserSession, now time.Time, idleThreshold time.Duration) map[string]float64 {
+	result := map[string]float64{
+		"Sessions Interactive":       float64(len(sessions)),
+		"Sessions Active":            0,
+		"Sessions Idle":              0,
+		"Sessions Disconnected":      0,
+		"Users Logged In":            0,
+		"Session Longest Duration s": 0,
+		"Session Average Duration s": 0,
+	}
+
+	users := make(map[string]bool)
+	var totalDuration float64
+	var timedSessions int
+	for _, session := range sessions {
+		users[session.User] = true
+
+		state := session.State
+		if state == SessionActive && idleThreshold > 0 && !session.LastInput.IsZero() && now.Sub(session.LastInput) >= idleThreshold {
+			state = SessionIdle
+		}
+		switch state {
+		case SessionActive:
+			result["Sessions Active"]++
+		case SessionIdle:
+			result["Sessions Idle"]++
+		case SessionDisconnected:
+			result["Sessions Disconnected"]++
+		}
+
+		if !session.LogonTime.IsZero() && now.After(session.LogonTime) {
+			duration := now.Sub(session.LogonTime).Seconds()
+			totalDuration += duration
+			timedSessions++
+			result["Session Longest Duration s"] = max(result["Session Longest Duration s"], duration)
+		}
+	}
+	result["Users Logged In"] = float64(len(users))
+	if timedSessions > 0 {
+		result["Session Average Duration s"] = totalDuration / float64(timedSessions)
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package collector
+
+import "time"
+
+const SessionSectionName = "session"
+
+// Normalized session states shared by every platform.
+const (
+	SessionActive       = "active"
+	SessionIdle         = "idle"
+	SessionDisconnected = "disconnected"
+)
+
+type SessionConfig struct {
+	// An active session without input for IdleThreshold counts as idle.
+	IdleThreshold time.Duration
+}
+
+// UserSession is an interactive logon session. LastInput is zero when the
+// platform cannot tell when the user last interacted with the session.
+type UserSession struct {
+	ID        string
+	User      string
+	State     string
+	LogonTime time.Time
+	LastInput time.Time
+}
+
+// sessionLister enumerates the interactive sessions of the machine, so the
+// state-to-metric mapping works with any source of sessions.
+type sessionLister interface {
+	ListSessions() ([]UserSession, error)
+}
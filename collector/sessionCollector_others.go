@@ -0,0 +1,45 @@
+//go:build !windows
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// utmpSessionLister lists the logged-in users from the utmp records. Every
+// record is an active session; the access time of its terminal tells when
+// the user last typed, like w(1) does.
+type utmpSessionLister struct{}
+
+func newSessionLister() sessionLister {
+	return &utmpSessionLister{}
+}
+
+func (lister *utmpSessionLister) ListSessions() ([]UserSession, error) {
+	users, err := host.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]UserSession, 0, len(users))
+	for _, user := range users {
+		session := UserSession{
+			ID:        user.Terminal,
+			User:      user.User,
+			State:     SessionActive,
+			LogonTime: time.Unix(int64(user.Started), 0),
+		}
+		if user.Terminal != "" && !strings.Contains(user.Terminal, "..") {
+			if info, err := os.Stat(filepath.Join("/dev", user.Terminal)); err == nil {
+				session.LastInput = terminalAccessTime(info)
+			}
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
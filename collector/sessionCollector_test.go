@@ -0,0 +1,111 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeSessions(t *testing.T) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		sessions      []UserSession
+		idleThreshold time.Duration
+		want          map[string]float64
+	}{
+		{
+			name:          "no sessions",
+			sessions:      nil,
+			idleThreshold: 15 * time.Minute,
+			want: map[string]float64{
+				"Sessions Interactive":       0,
+				"Sessions Active":            0,
+				"Sessions Idle":              0,
+				"Sessions Disconnected":      0,
+				"Users Logged In":            0,
+				"Session Longest Duration s": 0,
+				"Session Average Duration s": 0,
+			},
+		},
+		{
+			name: "active, idle and disconnected",
+			sessions: []UserSession{
+				{ID: "1", User: "alice", State: SessionActive, LogonTime: now.Add(-time.Hour), LastInput: now.Add(-time.Minute)},
+				{ID: "2", User: "bob", State: SessionActive, LogonTime: now.Add(-3 * time.Hour), LastInput: now.Add(-30 * time.Minute)},
+				{ID: "3", User: "carol", State: SessionDisconnected, LogonTime: now.Add(-2 * time.Hour)},
+			},
+			idleThreshold: 15 * time.Minute,
+			want: map[string]float64{
+				"Sessions Interactive":       3,
+				"Sessions Active":            1,
+				"Sessions Idle":              1,
+				"Sessions Disconnected":      1,
+				"Users Logged In":            3,
+				"Session Longest Duration s": 3 * 3600,
+				"Session Average Duration s": 2 * 3600,
+			},
+		},
+		{
+			name: "unknown last input stays active",
+			sessions: []UserSession{
+				{ID: "1", User: "alice", State: SessionActive, LogonTime: now.Add(-time.Hour)},
+			},
+			idleThreshold: 15 * time.Minute,
+			want: map[string]float64{
+				"Sessions Interactive":       1,
+				"Sessions Active":            1,
+				"Sessions Idle":              0,
+				"Sessions Disconnected":      0,
+				"Users Logged In":            1,
+				"Session Longest Duration s": 3600,
+				"Session Average Duration s": 3600,
+			},
+		},
+		{
+			name: "idle detection disabled",
+			sessions: []UserSession{
+				{ID: "1", User: "alice", State: SessionActive, LogonTime: now.Add(-time.Hour), LastInput: now.Add(-time.Hour)},
+			},
+			idleThreshold: 0,
+			want: map[string]float64{
+				"Sessions Interactive":       1,
+				"Sessions Active":            1,
+				"Sessions Idle":              0,
+				"Sessions Disconnected":      0,
+				"Users Logged In":            1,
+				"Session Longest Duration s": 3600,
+				"Session Average Duration s": 3600,
+			},
+		},
+		{
+			name: "one user with two sessions and one without logon time",
+			sessions: []UserSession{
+				{ID: "1", User: "alice", State: SessionActive, LogonTime: now.Add(-4 * time.Hour)},
+				{ID: "2", User: "alice", State: SessionDisconnected},
+			},
+			idleThreshold: 15 * time.Minute,
+			want: map[string]float64{
+				"Sessions Interactive":       2,
+				"Sessions Active":            1,
+				"Sessions Idle":              0,
+				"Sessions Disconnected":      1,
+				"Users Logged In":            1,
+				"Session Longest Duration s": 4 * 3600,
+				"Session Average Duration s": 4 * 3600,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := summarizeSessions(test.sessions, now, test.idleThreshold)
+			if len(got) != len(test.want) {
+				t.Fatalf("got %d metrics, want %d: %v", len(got), len(test.want), got)
+			}
+			for metric, want := range test.want {
+				if got[metric] != want {
+					t.Errorf("%s = %v, want %v", metric, got[metric], want)
+				}
+			}
+		})
+	}
+}
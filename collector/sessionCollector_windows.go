@@ -0,0 +1,61 @@
+//go:build windows
+
+package collector
+
+import (
+	"if-win-dex-agent/internal/headers/wtsapi32"
+	"log/slog"
+	"strconv"
+)
+
+// wtsSessionLister lists the sessions of the local Remote Desktop Services
+// server, which also covers the console session.
+type wtsSessionLister struct{}
+
+func newSessionLister() sessionLister {
+	return &wtsSessionLister{}
+}
+
+func (lister *wtsSessionLister) ListSessions() ([]UserSession, error) {
+	server, err := wtsapi32.WTSOpenServer("")
+	if err != nil {
+		return nil, err
+	}
+	defer wtsapi32.WTSCloseServer(server) //nolint:errcheck
+
+	wtsSessions, err := wtsapi32.WTSEnumerateSessionsEx(server, slog.Default())
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]UserSession, 0, len(wtsSessions))
+	for _, wtsSession := range wtsSessions {
+		// Services and listener sessions have no user logged on.
+		if wtsSession.UserName == "" {
+			continue
+		}
+		session := UserSession{
+			ID:   strconv.FormatUint(uint64(wtsSession.SessionID), 10),
+			User: wtsSession.UserName,
+		}
+		if wtsSession.DomainName != "" {
+			session.User = wtsSession.DomainName + `\` + wtsSession.UserName
+		}
+		switch wtsapi32.WTSSessionStates[wtsSession.State] {
+		case "active":
+			session.State = SessionActive
+		case "disconnected":
+			session.State = SessionDisconnected
+		default:
+			session.State = SessionIdle
+		}
+		if times, err := wtsapi32.WTSQuerySessionTimes(server, wtsSession.SessionID); err == nil {
+			session.LogonTime = times.LogonTime
+			session.LastInput = times.LastInputTime
+		} else {
+			slog.Debug("Failed to query session times", "session", session.ID, "error", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
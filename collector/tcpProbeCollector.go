@@ -0,0 +1,150 @@
+package collector
+
+import (
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+type TCPProbeCollector struct {
+	config TCPProbeConfig
+	// windows holds the attempts since the previous collection and results
+	// the attempts reported by the last one, both keyed by target name.
+	mutex   sync.Mutex
+	windows map[string]*tcpProbeWindow
+	results map[string]tcpProbeWindow
+}
+
+func NewTCPProbeCollector(config TCPProbeConfig) *TCPProbeCollector {
+	collector := &TCPProbeCollector{
+		config:  config,
+		windows: make(map[string]*tcpProbeWindow),
+		results: make(map[string]tcpProbeWindow),
+	}
+	for _, target := range config.Targets {
+		collector.windows[target.Name] = &tcpProbeWindow{}
+	}
+	return collector
+}
+
+// LoadTCPProbeConfig reads the probe targets from [tcp_probe:<name>] sections,
+// for example:
+//
+//	[tcp_probe:SQL]
+//	address = sql01.example.com:1433
+//	timeout = 5s
+//	interval = 30s
+func LoadTCPProbeConfig(p *configparser.ConfigParser) TCPProbeConfig {
+	config := TCPProbeConfig{}
+	for _, section := range getSectionsWithPrefix(p, TCPProbeSectionPrefix) {
+		target := TCPProbeTarget{
+			Name:     strings.TrimSpace(strings.TrimPrefix(section, TCPProbeSectionPrefix)),
+			Address:  getConfigString(p, section, "address", ""),
+			Timeout:  getConfigDuration(p, section, "timeout", 5*time.Second),
+			Interval: getConfigDuration(p, section, "interval", 0),
+		}
+		if _, _, err := net.SplitHostPort(target.Address); err != nil {
+			slog.Error("TCP probe needs a host:port address, skipping it", "probe", target.Name, "error", err)
+			continue
+		}
+		config.Targets = append(config.Targets, target)
+	}
+	return config
+}
+
+// Start probes every target with an interval on its own schedule, in the
+// background and independent of the collection interval.
+func (collector *TCPProbeCollector) Start() {
+	for _, target := range collector.config.Targets {
+		if target.Interval <= 0 {
+			continue
+		}
+		go func() {
+			ticker := time.NewTicker(target.Interval)
+			defer ticker.Stop()
+			for {
+				collector.probe(target)
+				<-ticker.C
+			}
+		}()
+	}
+}
+
+// probe connects to the target once and adds the attempt to its window.
+func (collector *TCPProbeCollector) probe(target TCPProbeTarget) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", target.Address, target.Timeout)
+	connectTime := time.Since(start)
+	if err == nil {
+		conn.Close()
+	} else {
+		slog.Warn("TCP probe failed", "probe", target.Name, "address", target.Address, "error", err)
+	}
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	window := collector.windows[target.Name]
+	window.Attempts++
+	if err != nil {
+		window.ConsecutiveFailures++
+		return
+	}
+	window.Successes++
+	window.ConnectTime += connectTime
+	window.ConsecutiveFailures = 0
+}
+
+// Collect closes the current window of every target. Targets that were not
+// attempted since the previous collection, because they have no interval or
+// it is longer than the collection interval, are probed now.
+func (collector *TCPProbeCollector) Collect() {
+	var wg sync.WaitGroup
+	for _, target := range collector.config.Targets {
+		collector.mutex.Lock()
+		attempted := collector.windows[target.Name].Attempts > 0
+		collector.mutex.Unlock()
+		if attempted {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			collector.probe(target)
+		}()
+	}
+	wg.Wait()
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	results := make(map[string]tcpProbeWindow)
+	for name, window := range collector.windows {
+		results[name] = *window
+		collector.windows[name] = &tcpProbeWindow{ConsecutiveFailures: window.ConsecutiveFailures}
+	}
+	collector.results = results
+}
+
+// GetProbeMetrics reports one instance per target. The connect latency is the
+// average of the successful attempts in the window.
+func (collector *TCPProbeCollector) GetProbeMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for name, window := range collector.results {
+		if window.Attempts == 0 {
+			continue
+		}
+		metrics := map[string]float64{
+			"TCP Probe Attempts":       float64(window.Attempts),
+			"TCP Success Ratio":        float64(window.Successes) / float64(window.Attempts),
+			"TCP Consecutive Failures": float64(window.ConsecutiveFailures),
+		}
+		if window.Successes > 0 {
+			metrics["TCP Connect ms"] = float64(window.ConnectTime.Microseconds()) / 1000 / float64(window.Successes)
+		}
+		result[name] = metrics
+	}
+	return &result
+}
@@ -0,0 +1,27 @@
+package collector
+
+import "time"
+
+const TCPProbeSectionPrefix = "tcp_probe:"
+
+// TCPProbeTarget is a host:port connected to every Interval. Targets without
+// an interval are probed once per collection.
+type TCPProbeTarget struct {
+	Name     string
+	Address  string
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+type TCPProbeConfig struct {
+	Targets []TCPProbeTarget
+}
+
+// tcpProbeWindow accumulates the attempts of a target between collections.
+type tcpProbeWindow struct {
+	Attempts    int
+	Successes   int
+	ConnectTime time.Duration
+	// ConsecutiveFailures carries over between windows until a connect succeeds.
+	ConsecutiveFailures int
+}
@@ -0,0 +1,16 @@
+package collector
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// terminalAccessTime returns the last read of a terminal device, which is
+// updated when the user types.
+func terminalAccessTime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Atim.Sec, stat.Atim.Nsec)
+	}
+	return info.ModTime()
+}
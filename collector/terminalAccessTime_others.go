@@ -0,0 +1,14 @@
+//go:build !linux && !windows
+
+package collector
+
+import (
+	"os"
+	"time"
+)
+
+// terminalAccessTime falls back to the modification time where the access
+// time is not exposed in a portable way.
+func terminalAccessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,392 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+var transactionVariablePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// transactionMaxBodyBytes caps the part of a response body that is read and
+// checked by the assertions and extractors of a step.
+const transactionMaxBodyBytes = 10 * 1024 * 1024
+
+type TransactionCollector struct {
+	config  TransactionConfig
+	lastRun map[string]time.Time
+	failing map[string]bool
+	// results holds the runs since the previous collection.
+	results map[string]transactionResult
+	events  []Event
+}
+
+func NewTransactionCollector(config TransactionConfig) *TransactionCollector {
+	return &TransactionCollector{
+		config:  config,
+		lastRun: make(map[string]time.Time),
+		failing: make(map[string]bool),
+		results: make(map[string]transactionResult),
+	}
+}
+
+// LoadTransactionConfig reads transactions from [transaction:<name>] sections
+// listing their steps, which are defined in [transaction_step:<name>.<step>]
+// sections, for example:
+//
+//	[transaction:Portal]
+//	steps = login, profile
+//	interval = 15m
+//	timeout = 30s
+//	var.username = probe-user
+//
+//	[transaction_step:Portal.login]
+//	method = POST
+//	url = https://portal.example.com/api/login
+//	header.Content-Type = application/json
+//	body = {"user": "${username}", "password": "${PORTAL_PASSWORD}"}
+//	extract.token = json:$.token
+//	expected_status = 200
+//
+//	[transaction_step:Portal.profile]
+//	url = https://portal.example.com/api/me
+//	header.Authorization = Bearer ${token}
+//	assert_contains = ${username}
+//
+// Variables that are not defined by var.* or an extraction are read from the
+// environment, so secrets do not have to be stored in the file.
+func LoadTransactionConfig(p *configparser.ConfigParser) TransactionConfig {
+	config := TransactionConfig{}
+	for _, section := range getSectionsWithPrefix(p, TransactionSectionPrefix) {
+		transaction := Transaction{
+			Name:               strings.TrimSpace(strings.TrimPrefix(section, TransactionSectionPrefix)),
+			Variables:          getConfigOptionsWithPrefix(p, section, "var."),
+			Timeout:            getConfigDuration(p, section, "timeout", 30*time.Second),
+			Interval:           getConfigDuration(p, section, "interval", 0),
+			InsecureSkipVerify: getConfigBool(p, section, "insecure_skip_verify", false),
+		}
+		valid := true
+		for _, stepName := range getConfigList(p, section, "steps") {
+			step, err := loadTransactionStep(p, TransactionStepSectionPrefix+transaction.Name+"."+stepName)
+			if err != nil {
+				slog.Error("Invalid transaction step, skipping the transaction", "transaction", transaction.Name, "step", stepName, "error", err)
+				valid = false
+				break
+			}
+			step.Name = stepName
+			transaction.Steps = append(transaction.Steps, step)
+		}
+		if !valid || len(transaction.Steps) == 0 {
+			continue
+		}
+		config.Transactions = append(config.Transactions, transaction)
+	}
+	return config
+}
+
+func loadTransactionStep(p *configparser.ConfigParser, section string) (TransactionStep, error) {
+	if !p.HasSection(section) {
+		return TransactionStep{}, fmt.Errorf("missing section [%s]", section)
+	}
+	step := TransactionStep{
+		Method:          strings.ToUpper(getConfigString(p, section, "method", http.MethodGet)),
+		URL:             getConfigString(p, section, "url", ""),
+		Headers:         getConfigOptionsWithPrefix(p, section, "header."),
+		Body:            getConfigString(p, section, "body", ""),
+		FollowRedirects: getConfigBool(p, section, "follow_redirects", true),
+		AssertContains:  getConfigList(p, section, "assert_contains"),
+	}
+	if step.URL == "" {
+		return step, errors.New("no url")
+	}
+	for _, status := range getConfigList(p, section, "expected_status") {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return step, fmt.Errorf("invalid expected_status: %w", err)
+		}
+		step.ExpectedStatus = append(step.ExpectedStatus, code)
+	}
+	for _, pattern := range getConfigList(p, section, "assert_regex") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return step, fmt.Errorf("invalid assert_regex: %w", err)
+		}
+		step.AssertRegex = append(step.AssertRegex, re)
+	}
+	for variable, rule := range getConfigOptionsWithPrefix(p, section, "extract.") {
+		kind, expression, _ := strings.Cut(rule, ":")
+		extractor := transactionExtractor{Variable: variable}
+		switch strings.ToLower(kind) {
+		case "json":
+			extractor.JSONPath = expression
+		case "regex":
+			re, err := regexp.Compile(expression)
+			if err != nil {
+				return step, fmt.Errorf("invalid extract.%s: %w", variable, err)
+			}
+			extractor.Regex = re
+		default:
+			return step, fmt.Errorf("extract.%s must start with json: or regex:", variable)
+		}
+		step.Extractors = append(step.Extractors, extractor)
+	}
+	return step, nil
+}
+
+// Collect runs every transaction that is due, concurrently.
+func (collector *TransactionCollector) Collect() {
+	now := time.Now()
+	results := make(map[string]transactionResult)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, transaction := range collector.config.Transactions {
+		if lastRun, ok := collector.lastRun[transaction.Name]; ok && now.Sub(lastRun) < transaction.Interval {
+			continue
+		}
+		collector.lastRun[transaction.Name] = now
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := runTransaction(transaction)
+			mutex.Lock()
+			results[transaction.Name] = result
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for _, transaction := range collector.config.Transactions {
+		result, ok := results[transaction.Name]
+		if !ok {
+			continue
+		}
+		if !result.Success && !collector.failing[transaction.Name] {
+			failedStep := transaction.Steps[result.FailedStep-1].Name
+			collector.events = append(collector.events, Event{
+				Timestamp: now,
+				Device:    transaction.Name,
+				Type:      EventTransactionFailed,
+				Message:   fmt.Sprintf("Transaction %s failed at step %s: %s", transaction.Name, failedStep, result.Error),
+				Data: map[string]interface{}{
+					"transaction": transaction.Name,
+					"step":        failedStep,
+					"error":       result.Error,
+				},
+			})
+		}
+		collector.failing[transaction.Name] = !result.Success
+	}
+	collector.results = results
+}
+
+// runTransaction runs the steps in order with a fresh cookie jar and stops at
+// the first failed step. The steps share one transport, so a connection can be
+// reused between them, and its idle connections are closed after the run.
+func runTransaction(transaction Transaction) transactionResult {
+	result := transactionResult{Success: true}
+	ctx, cancel := context.WithTimeout(context.Background(), transaction.Timeout)
+	defer cancel()
+
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: transaction.InsecureSkipVerify},
+	}
+	defer transport.CloseIdleConnections()
+	jar, _ := cookiejar.New(nil)
+	variables := make(map[string]string)
+	for name, value := range transaction.Variables {
+		variables[name] = value
+	}
+
+	start := time.Now()
+	for index, step := range transaction.Steps {
+		stepResult, err := runTransactionStep(ctx, transport, step, jar, variables)
+		result.Steps = append(result.Steps, stepResult)
+		if err != nil {
+			slog.Warn("Transaction step failed", "transaction", transaction.Name, "step", step.Name, "error", err)
+			result.Success = false
+			result.FailedStep = index + 1
+			result.Error = err.Error()
+			break
+		}
+	}
+	result.TotalTime = time.Since(start)
+	return result
+}
+
+func runTransactionStep(ctx context.Context, transport http.RoundTripper, step TransactionStep, jar http.CookieJar, variables map[string]string) (transactionStepResult, error) {
+	result := transactionStepResult{Name: step.Name}
+	client := &http.Client{Jar: jar, Transport: transport}
+	if !step.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(expandTransactionVariables(step.Body, variables))
+	}
+	request, err := http.NewRequestWithContext(ctx, step.Method, expandTransactionVariables(step.URL, variables), body)
+	if err != nil {
+		return result, err
+	}
+	for name, value := range step.Headers {
+		request.Header.Set(name, expandTransactionVariables(value, variables))
+	}
+
+	start := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		result.Duration = time.Since(start)
+		return result, err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(response.Body, transactionMaxBodyBytes))
+	result.Duration = time.Since(start)
+	result.StatusCode = response.StatusCode
+	if err != nil {
+		return result, err
+	}
+
+	if len(step.ExpectedStatus) > 0 && !slices.Contains(step.ExpectedStatus, response.StatusCode) {
+		return result, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	if len(step.ExpectedStatus) == 0 && response.StatusCode >= 400 {
+		return result, errors.New(response.Status)
+	}
+	for _, text := range step.AssertContains {
+		text = expandTransactionVariables(text, variables)
+		if !bytes.Contains(content, []byte(text)) {
+			return result, fmt.Errorf("response does not contain %q", text)
+		}
+	}
+	for _, pattern := range step.AssertRegex {
+		if !pattern.Match(content) {
+			return result, fmt.Errorf("response does not match %q", pattern.String())
+		}
+	}
+	for _, extractor := range step.Extractors {
+		value, err := extractor.extract(content)
+		if err != nil {
+			return result, fmt.Errorf("extract %s: %w", extractor.Variable, err)
+		}
+		variables[extractor.Variable] = value
+	}
+	result.Success = true
+	return result, nil
+}
+
+// expandTransactionVariables replaces ${name} with the variable, or with the
+// environment variable of that name when no such variable is set.
+func expandTransactionVariables(text string, variables map[string]string) string {
+	return transactionVariablePattern.ReplaceAllStringFunc(text, func(reference string) string {
+		name := reference[2 : len(reference)-1]
+		if value, ok := variables[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	})
+}
+
+func (extractor *transactionExtractor) extract(content []byte) (string, error) {
+	if extractor.Regex != nil {
+		match := extractor.Regex.FindSubmatch(content)
+		if match == nil {
+			return "", fmt.Errorf("no match for %q", extractor.Regex.String())
+		}
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+		return string(match[0]), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return "", err
+	}
+	value, err := lookupJSONPath(document, extractor.JSONPath)
+	if err != nil {
+		return "", err
+	}
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// lookupJSONPath follows a path of object keys and array indexes such as
+// $.data.items[0].id through a decoded JSON document.
+func lookupJSONPath(document interface{}, path string) (interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "$"), ".")
+	path = strings.ReplaceAll(strings.ReplaceAll(path, "[", "."), "]", "")
+	current := document
+	if path == "" {
+		return current, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("no key %q in %s", key, path)
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("no index %q in %s", key, path)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("cannot look up %q in %s", key, path)
+		}
+	}
+	return current, nil
+}
+
+// GetEvents returns the events detected since the last call and clears them.
+func (collector *TransactionCollector) GetEvents() []Event {
+	events := collector.events
+	collector.events = nil
+	return events
+}
+
+// GetTransactionMetrics reports one instance per transaction with the total
+// and per-step timings of the runs since the previous collection.
+func (collector *TransactionCollector) GetTransactionMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for name, run := range collector.results {
+		metrics := map[string]float64{
+			"Transaction Total ms":    float64(run.TotalTime.Microseconds()) / 1000,
+			"Transaction Success":     boolMetric(run.Success),
+			"Transaction Failed Step": float64(run.FailedStep),
+		}
+		for _, step := range run.Steps {
+			metrics["Step "+step.Name+" ms"] = float64(step.Duration.Microseconds()) / 1000
+			metrics["Step "+step.Name+" Status Code"] = float64(step.StatusCode)
+			metrics["Step "+step.Name+" Success"] = boolMetric(step.Success)
+		}
+		result[name] = metrics
+	}
+	return &result
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"regexp"
+	"time"
+)
+
+const TransactionSectionPrefix = "transaction:"
+const TransactionStepSectionPrefix = "transaction_step:"
+
+// Transaction is a user journey of ordered HTTP steps that share cookies and
+// variables. It runs when Interval has passed since its previous run, which
+// is every collection when Interval is shorter than the collection interval.
+type Transaction struct {
+	Name               string
+	Steps              []TransactionStep
+	Variables          map[string]string
+	Timeout            time.Duration
+	Interval           time.Duration
+	InsecureSkipVerify bool
+}
+
+// TransactionStep is one request of a transaction. ${name} references in the
+// URL, headers, body and content assertions are replaced with variables.
+type TransactionStep struct {
+	Name            string
+	Method          string
+	URL             string
+	Headers         map[string]string
+	Body            string
+	FollowRedirects bool
+	Extractors      []transactionExtractor
+	ExpectedStatus  []int
+	AssertContains  []string
+	AssertRegex     []*regexp.Regexp
+}
+
+// transactionExtractor stores a value of the response in a variable, either
+// from a JSON path such as $.data.items[0].id or from the first group of a
+// regular expression.
+type transactionExtractor struct {
+	Variable string
+	JSONPath string
+	Regex    *regexp.Regexp
+}
+
+type TransactionConfig struct {
+	Transactions []Transaction
+}
+
+type transactionStepResult struct {
+	Name       string
+	Duration   time.Duration
+	StatusCode int
+	Success    bool
+}
+
+// transactionResult is a run of a transaction. The steps after a failed step
+// are not run.
+type transactionResult struct {
+	Steps     []transactionStepResult
+	TotalTime time.Duration
+	Success   bool
+	// FailedStep is the 1-based index of the failed step.
+	FailedStep int
+	Error      string
+}
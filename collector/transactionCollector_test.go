@@ -0,0 +1,200 @@
+package collector
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(`{"data": {"items": [{"id": 7}, {"id": 9, "tags": ["a", "b"]}], "token": "abc"}}`), &document); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path    string
+		want    interface{}
+		wantErr bool
+	}{
+		{path: "$.data.token", want: "abc"},
+		{path: "data.items[1].id", want: 9.0},
+		{path: "$.data.items[1].tags[0]", want: "a"},
+		{path: "$.data.missing", wantErr: true},
+		{path: "$.data.items[2].id", wantErr: true},
+		{path: "$.data.items[-1]", wantErr: true},
+		{path: "$.data.token.length", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			got, err := lookupJSONPath(document, test.path)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && got != test.want {
+				t.Errorf("value = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTransactionExtractor(t *testing.T) {
+	content := []byte(`{"token": "abc", "expires": 3600, "user": {"id": 7}} <input name="csrf" value="x1y2">`)
+	jsonContent := content[:strings.Index(string(content), " <input")]
+	tests := []struct {
+		name      string
+		extractor transactionExtractor
+		content   []byte
+		want      string
+		wantErr   bool
+	}{
+		{name: "json string", extractor: transactionExtractor{JSONPath: "$.token"}, content: jsonContent, want: "abc"},
+		{name: "json number keeps its text", extractor: transactionExtractor{JSONPath: "$.expires"}, content: jsonContent, want: "3600"},
+		{name: "json object is encoded", extractor: transactionExtractor{JSONPath: "$.user"}, content: jsonContent, want: `{"id":7}`},
+		{name: "json missing key", extractor: transactionExtractor{JSONPath: "$.refresh"}, content: jsonContent, wantErr: true},
+		{name: "json invalid body", extractor: transactionExtractor{JSONPath: "$.token"}, content: []byte("<html>"), wantErr: true},
+		{name: "regex first group", extractor: transactionExtractor{Regex: regexp.MustCompile(`name="csrf" value="([^"]+)"`)}, content: content, want: "x1y2"},
+		{name: "regex whole match", extractor: transactionExtractor{Regex: regexp.MustCompile(`x\dy\d`)}, content: content, want: "x1y2"},
+		{name: "regex no match", extractor: transactionExtractor{Regex: regexp.MustCompile(`session=(\w+)`)}, content: content, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.extractor.extract(test.content)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("value = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExpandTransactionVariables(t *testing.T) {
+	t.Setenv("TRANSACTION_TEST_PASSWORD", "from-env")
+	t.Setenv("TRANSACTION_TEST_USER", "env-user")
+	variables := map[string]string{"TRANSACTION_TEST_USER": "alice", "token": "abc"}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Bearer ${token}", want: "Bearer abc"},
+		{text: "user=${TRANSACTION_TEST_USER}&password=${TRANSACTION_TEST_PASSWORD}", want: "user=alice&password=from-env"},
+		{text: "${TRANSACTION_TEST_UNSET}", want: ""},
+		{text: "no references, $token or ${}", want: "no references, $token or ${}"},
+	}
+	for _, test := range tests {
+		if got := expandTransactionVariables(test.text, variables); got != test.want {
+			t.Errorf("expandTransactionVariables(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func newTransactionTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		if string(body) != "user=alice&password=secret" {
+			http.Error(writer, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(writer, &http.Cookie{Name: "session", Value: "s1"})
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write([]byte(`{"data": {"token": "t-123", "user": {"id": 7}}}`))
+	})
+	mux.HandleFunc("GET /profile", func(writer http.ResponseWriter, request *http.Request) {
+		cookie, err := request.Cookie("session")
+		if err != nil || cookie.Value != "s1" || request.Header.Get("Authorization") != "Bearer t-123" {
+			http.Error(writer, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		writer.Write([]byte(`{"name": "alice", "id": 7}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunTransaction(t *testing.T) {
+	server := newTransactionTestServer(t)
+	login := func(password string, tokenPath string) TransactionStep {
+		return TransactionStep{
+			Name:            "login",
+			Method:          http.MethodPost,
+			URL:             server.URL + "/login",
+			Body:            "user=${user}&password=" + password,
+			FollowRedirects: true,
+			Extractors: []transactionExtractor{
+				{Variable: "token", JSONPath: tokenPath},
+				{Variable: "userId", JSONPath: "$.data.user.id"},
+			},
+		}
+	}
+	profile := TransactionStep{
+		Name:            "profile",
+		Method:          http.MethodGet,
+		URL:             server.URL + "/profile",
+		Headers:         map[string]string{"Authorization": "Bearer ${token}"},
+		FollowRedirects: true,
+		AssertContains:  []string{"${user}"},
+		AssertRegex:     []*regexp.Regexp{regexp.MustCompile(`"id":\s*7`)},
+	}
+	tests := []struct {
+		name           string
+		steps          []TransactionStep
+		wantSuccess    bool
+		wantFailedStep int
+		wantStatuses   []int
+	}{
+		{
+			name:         "login then profile",
+			steps:        []TransactionStep{login("secret", "$.data.token"), profile},
+			wantSuccess:  true,
+			wantStatuses: []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:           "wrong password stops at the first step",
+			steps:          []TransactionStep{login("wrong", "$.data.token"), profile},
+			wantFailedStep: 1,
+			wantStatuses:   []int{http.StatusUnauthorized},
+		},
+		{
+			name:           "missing token fails the extraction",
+			steps:          []TransactionStep{login("secret", "$.data.accessToken"), profile},
+			wantFailedStep: 1,
+			wantStatuses:   []int{http.StatusOK},
+		},
+		{
+			name:           "profile without a token is rejected",
+			steps:          []TransactionStep{login("secret", "$.data.user.id"), profile},
+			wantFailedStep: 2,
+			wantStatuses:   []int{http.StatusOK, http.StatusUnauthorized},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transaction := Transaction{
+				Name:      "Portal",
+				Steps:     test.steps,
+				Variables: map[string]string{"user": "alice"},
+				Timeout:   5 * time.Second,
+			}
+			result := runTransaction(transaction)
+			if result.Success != test.wantSuccess || result.FailedStep != test.wantFailedStep {
+				t.Errorf("success = %v, failed step = %d (%s), want %v, %d", result.Success, result.FailedStep, result.Error, test.wantSuccess, test.wantFailedStep)
+			}
+			statuses := make([]int, 0, len(result.Steps))
+			for _, step := range result.Steps {
+				statuses = append(statuses, step.StatusCode)
+			}
+			if !slices.Equal(statuses, test.wantStatuses) {
+				t.Errorf("step statuses = %v, want %v", statuses, test.wantStatuses)
+			}
+		})
+	}
+}
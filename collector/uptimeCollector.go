@@ -0,0 +1,186 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"if-win-dex-agent/cache"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+// Keys of the uptime state persisted across agent restarts.
+const (
+	stateLastBootTime  = "uptime.last_boot_time"
+	stateLastHeartbeat = "uptime.last_heartbeat"
+	stateCleanShutdown = "uptime.clean_shutdown"
+	stateRebootHistory = "uptime.reboot_history"
+)
+
+// bootTimeTolerance absorbs the jitter of a boot time derived from the uptime.
+const bootTimeTolerance = 5 * time.Second
+
+const rebootHistoryWindow = 30 * 24 * time.Hour
+
+type UptimeCollector struct {
+	stateService *cache.StateService
+	bootTime     time.Time
+	uptime       time.Duration
+	reboots      []time.Time
+	events       []Event
+	checked      bool
+}
+
+func NewUptimeCollector(stateService *cache.StateService) *UptimeCollector {
+	return &UptimeCollector{stateService: stateService}
+}
+
+// Collect reads the boot time and records a heartbeat. On the first call it
+// compares the boot time with the one persisted by the previous agent run to
+// detect a reboot, which was clean when that run recorded a graceful shutdown.
+func (collector *UptimeCollector) Collect() {
+	bootTime, err := host.BootTime()
+	if err != nil {
+		slog.Error("Error fetching boot time", "error", err)
+		return
+	}
+	now := time.Now()
+	collector.bootTime = time.Unix(int64(bootTime), 0)
+	collector.uptime = now.Sub(collector.bootTime)
+	if collector.stateService == nil {
+		return
+	}
+
+	if !collector.checked {
+		collector.checked = true
+		collector.reboots = collector.loadRebootHistory()
+		collector.detectReboot()
+		collector.stateService.SetValue(stateLastBootTime, strconv.FormatInt(collector.bootTime.Unix(), 10))
+		collector.stateService.DeleteValue(stateCleanShutdown)
+	}
+	collector.stateService.SetValue(stateLastHeartbeat, strconv.FormatInt(now.Unix(), 10))
+}
+
+func (collector *UptimeCollector) detectReboot() {
+	lastBootTime, ok := collector.getStateTime(stateLastBootTime)
+	if !ok {
+		return
+	}
+	difference := collector.bootTime.Sub(lastBootTime)
+	if difference < bootTimeTolerance && difference > -bootTimeTolerance {
+		return
+	}
+
+	_, clean := collector.stateService.GetValue(stateCleanShutdown)
+	data := map[string]interface{}{
+		"bootTime":         collector.bootTime.UnixMilli(),
+		"previousBootTime": lastBootTime.UnixMilli(),
+		"clean":            clean,
+	}
+	message := fmt.Sprintf("Unexpected reboot at %s, no graceful agent shutdown was recorded", collector.bootTime.Format(time.RFC3339))
+	if clean {
+		message = fmt.Sprintf("Clean reboot at %s", collector.bootTime.Format(time.RFC3339))
+	}
+	// The last heartbeat is the latest moment the machine was known to be up.
+	if lastHeartbeat, ok := collector.getStateTime(stateLastHeartbeat); ok && collector.bootTime.After(lastHeartbeat) {
+		downtime := collector.bootTime.Sub(lastHeartbeat)
+		data["downtimeSeconds"] = downtime.Seconds()
+		message += fmt.Sprintf(", down for about %s", downtime.Round(time.Second))
+	}
+
+	collector.events = append(collector.events, Event{
+		Timestamp: time.Now(),
+		Device:    "",
+		Type:      EventReboot,
+		Message:   message,
+		Data:      data,
+	})
+	collector.reboots = append(collector.reboots, collector.bootTime)
+	collector.saveRebootHistory()
+}
+
+// RecordShutdown marks the agent as stopped gracefully, so the next boot is
+// reported as a clean reboot.
+func (collector *UptimeCollector) RecordShutdown() {
+	if collector.stateService == nil {
+		return
+	}
+	collector.stateService.SetValue(stateLastHeartbeat, strconv.FormatInt(time.Now().Unix(), 10))
+	collector.stateService.SetValue(stateCleanShutdown, "true")
+}
+
+func (collector *UptimeCollector) getStateTime(key string) (time.Time, bool) {
+	value, ok := collector.stateService.GetValue(key)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+func (collector *UptimeCollector) loadRebootHistory() []time.Time {
+	reboots := make([]time.Time, 0)
+	value, ok := collector.stateService.GetValue(stateRebootHistory)
+	if !ok {
+		return reboots
+	}
+	var seconds []int64
+	if err := json.Unmarshal([]byte(value), &seconds); err != nil {
+		slog.Error("Invalid reboot history in agent state", "error", err)
+		return reboots
+	}
+	for _, second := range seconds {
+		reboots = append(reboots, time.Unix(second, 0))
+	}
+	return reboots
+}
+
+func (collector *UptimeCollector) saveRebootHistory() {
+	seconds := make([]int64, 0, len(collector.reboots))
+	recent := make([]time.Time, 0, len(collector.reboots))
+	for _, reboot := range collector.reboots {
+		if time.Since(reboot) <= rebootHistoryWindow {
+			seconds = append(seconds, reboot.Unix())
+			recent = append(recent, reboot)
+		}
+	}
+	collector.reboots = recent
+	data, _ := json.Marshal(seconds)
+	collector.stateService.SetValue(stateRebootHistory, string(data))
+}
+
+func (collector *UptimeCollector) GetUptimeMetrics() *map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	if collector.bootTime.IsZero() {
+		return &result
+	}
+	result[""] = map[string]float64{
+		"System Uptime s": collector.uptime.Seconds(),
+	}
+	if collector.stateService != nil {
+		rebootsLast7Days, rebootsLast30Days := 0, 0
+		for _, reboot := range collector.reboots {
+			if time.Since(reboot) <= 7*24*time.Hour {
+				rebootsLast7Days++
+			}
+			if time.Since(reboot) <= rebootHistoryWindow {
+				rebootsLast30Days++
+			}
+		}
+		result[""]["Reboots Last 7 Days"] = float64(rebootsLast7Days)
+		result[""]["Reboots Last 30 Days"] = float64(rebootsLast30Days)
+	}
+	return &result
+}
+
+// GetEvents returns the events detected since the last call and clears them.
+func (collector *UptimeCollector) GetEvents() []Event {
+	events := collector.events
+	collector.events = nil
+	return events
+}
@@ -1 +1,109 @@
 package collector
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigkevmcd/go-configparser"
+)
+
+// getConfigString returns the option value or defaultValue when the section or
+// option is missing or empty.
+func getConfigString(p *configparser.ConfigParser, section string, option string, defaultValue string) string {
+	if p == nil {
+		return defaultValue
+	}
+	value, err := p.Get(section, option)
+	if err != nil || strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+	return strings.TrimSpace(value)
+}
+
+func getConfigBool(p *configparser.ConfigParser, section string, option string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(getConfigString(p, section, option, strconv.FormatBool(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getConfigInt(p *configparser.ConfigParser, section string, option string, defaultValue int) int {
+	value, err := strconv.Atoi(getConfigString(p, section, option, strconv.Itoa(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getConfigFloat(p *configparser.ConfigParser, section string, option string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(getConfigString(p, section, option, ""), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getConfigDuration accepts Go duration strings ("30s", "5m") as well as plain
+// numbers, which are read as seconds.
+func getConfigDuration(p *configparser.ConfigParser, section string, option string, defaultValue time.Duration) time.Duration {
+	value := getConfigString(p, section, option, "")
+	if value == "" {
+		return defaultValue
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return duration
+}
+
+// getConfigList splits a comma separated option into trimmed, non-empty values.
+func getConfigList(p *configparser.ConfigParser, section string, option string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(getConfigString(p, section, option, ""), ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getSectionsWithPrefix returns every section such as [application:Teams]
+// whose name starts with prefix, in the sorted order of the config parser.
+func getSectionsWithPrefix(p *configparser.ConfigParser, prefix string) []string {
+	result := make([]string, 0)
+	if p == nil {
+		return result
+	}
+	for _, section := range p.Sections() {
+		if strings.HasPrefix(section, prefix) && strings.TrimSpace(strings.TrimPrefix(section, prefix)) != "" {
+			result = append(result, section)
+		}
+	}
+	return result
+}
+
+// getConfigOptionsWithPrefix returns the options of a section such as
+// header.Accept whose name starts with prefix, keyed by the rest of the name.
+func getConfigOptionsWithPrefix(p *configparser.ConfigParser, section string, prefix string) map[string]string {
+	result := make(map[string]string)
+	if p == nil {
+		return result
+	}
+	options, err := p.Options(section)
+	if err != nil {
+		return result
+	}
+	for _, option := range options {
+		if name := strings.TrimPrefix(option, prefix); name != option && name != "" {
+			result[name] = getConfigString(p, section, option, "")
+		}
+	}
+	return result
+}
@@ -8,15 +8,24 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
 const METRIC_DATA_API = "/api/v2/metric-data-receive"
+const LOG_DATA_API = "/api/v1/customprojectrawdata"
+const INCIDENT_DATA_API = "/api/v1/incidentEventReceive"
+const DEPLOYMENT_DATA_API = "/api/v1/deploymentEventReceive"
 const CHUNK_SIZE = 2 * 1024 * 1024
 const MAX_PACKET_SIZE = 10000000
 const HTTP_RETRY_TIMES = 15
 const HTTP_RETRY_INTERVAL = 60
 
+// Agent type of the log receive API. The incident and deployment receive APIs
+// take no agent type.
+const LOG_AGENT_TYPE = "LogStreaming"
+
 type InsightFinderClient struct {
 	Url        string
 	Username   string
@@ -98,6 +107,51 @@ func (client *InsightFinderClient) SendMetricData(instanceDataMap *InstanceDataM
 	client.sendDataToIF(jData, METRIC_DATA_API)
 }
 
+// SendLogData sends log entries to the LOG project of the client, split into
+// chunks of at most CHUNK_SIZE bytes. It stops at the first chunk that could
+// not be sent and returns its error.
+func (client *InsightFinderClient) SendLogData(logData []LogData) error {
+	return sendRecords(client, logData, "metricData", LOG_AGENT_TYPE, LOG_DATA_API)
+}
+
+// SendIncidentData sends incidents and alerts to the INCIDENT or ALERT project
+// of the client, split into chunks of at most CHUNK_SIZE bytes.
+func (client *InsightFinderClient) SendIncidentData(incidentData []IncidentData) error {
+	return sendRecords(client, incidentData, "incidentData", "", INCIDENT_DATA_API)
+}
+
+// SendDeploymentData sends deployment and change events to the DEPLOYMENT
+// project of the client, split into chunks of at most CHUNK_SIZE bytes.
+func (client *InsightFinderClient) SendDeploymentData(deploymentData []DeploymentData) error {
+	return sendRecords(client, deploymentData, "deploymentData", "", DEPLOYMENT_DATA_API)
+}
+
+// sendRecords posts records in chunks as the JSON value of the dataField form
+// field, next to the credentials and project of the client and the agent
+// type when the endpoint takes one.
+func sendRecords[T any](client *InsightFinderClient, records []T, dataField string, agentType string, receiveEndpoint string) error {
+	for _, chunk := range ChunkRecords(records, CHUNK_SIZE) {
+		jData, err := json.Marshal(chunk)
+		if err != nil {
+			slog.Error("Failed to encode records", "endpoint", receiveEndpoint, "error", err)
+			return err
+		}
+		form := url.Values{
+			"userName":    {client.Username},
+			"licenseKey":  {client.LicenseKey},
+			"projectName": {client.Project},
+			dataField:     {string(jData)},
+		}
+		if agentType != "" {
+			form.Set("agentType", agentType)
+		}
+		if err := client.sendFormToIF(form, receiveEndpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (client *InsightFinderClient) sendDataToIF(data []byte, receiveEndpoint string) {
 	slog.Info("-------- Sending data to InsightFinder --------")
 
@@ -122,6 +176,73 @@ func (client *InsightFinderClient) sendDataToIF(data []byte, receiveEndpoint str
 	slog.Info(string(response))
 }
 
+// sendFormToIF posts form-encoded data, which the receive APIs of log,
+// incident and deployment projects expect. Unlike sendDataToIF it returns an
+// error rather than panicking, so the caller can send the data again later.
+func (client *InsightFinderClient) sendFormToIF(form url.Values, receiveEndpoint string) error {
+	data := form.Encode()
+	if len(data) > MAX_PACKET_SIZE {
+		slog.Error("The packet size is too large", "size", len(data), "endpoint", receiveEndpoint)
+		return fmt.Errorf("packet of %d bytes is too large", len(data))
+	}
+
+	endpoint := FormCompleteURL(client.Url, receiveEndpoint)
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+	slog.Info("[LOG] Prepare to send out " + fmt.Sprint(len(data)) + " bytes data to IF:" + endpoint)
+	response, err := trySendRequest(http.MethodPost, endpoint, []byte(data), headers)
+	if err != nil {
+		slog.Error("Failed to send data to InsightFinder", "endpoint", receiveEndpoint, "error", err)
+		return err
+	}
+	slog.Info(string(response))
+	return nil
+}
+
+// trySendRequest sends a request like SendRequest, retrying transport
+// failures, but returns an error instead of panicking when every attempt
+// failed or the response status is not 2xx.
+func trySendRequest(operation string, endpoint string, data []byte, headers map[string]string) ([]byte, error) {
+	// Skip certificate verification.
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	var res *http.Response
+	var err error
+	for i := 0; i < HTTP_RETRY_TIMES; i++ {
+		if i > 0 {
+			slog.Warn("Retrying request to InsightFinder", "endpoint", endpoint, "retryIn", HTTP_RETRY_INTERVAL*time.Second, "error", err)
+			time.Sleep(HTTP_RETRY_INTERVAL * time.Second)
+		}
+		// A request body can only be read once, so every attempt gets its own.
+		var request *http.Request
+		request, err = http.NewRequest(operation, endpoint, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		for k := range headers {
+			request.Header.Add(k, headers[k])
+		}
+		res, err = client.Do(request)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no response after %d attempts: %w", HTTP_RETRY_TIMES, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return body, fmt.Errorf("unexpected status %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
 func SendRequest(operation string, endpoint string, form io.Reader, headers map[string]string) ([]byte, http.Header) {
 	newRequest, err := http.NewRequest(
 		operation,
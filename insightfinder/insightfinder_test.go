@@ -0,0 +1,45 @@
+package insightfinder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendRecords(t *testing.T) {
+	status := http.StatusOK
+	agentTypes := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		agentTypes[r.URL.Path] = r.PostForm["agentType"]
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	client := CreateInsightFinderClient(server.URL, "user", "key", "project")
+
+	logData := []LogData{{TimeStamp: 1, Tag: "host", Data: "line"}}
+	if err := client.SendLogData(logData); err != nil {
+		t.Fatalf("SendLogData = %v, want nil", err)
+	}
+	if got := agentTypes[LOG_DATA_API]; len(got) != 1 || got[0] != LOG_AGENT_TYPE {
+		t.Errorf("log agentType = %v, want [%s]", got, LOG_AGENT_TYPE)
+	}
+	incidentData := []IncidentData{{TimeStamp: 1, InstanceName: "host", Data: "incident"}}
+	if err := client.SendIncidentData(incidentData); err != nil {
+		t.Fatalf("SendIncidentData = %v, want nil", err)
+	}
+	if got, ok := agentTypes[INCIDENT_DATA_API]; !ok || len(got) != 0 {
+		t.Errorf("incident agentType = %v (received %v), want none", got, ok)
+	}
+
+	status = http.StatusInternalServerError
+	if err := client.SendLogData(logData); err == nil {
+		t.Error("SendLogData with status 500 = nil, want an error")
+	}
+	status = http.StatusForbidden
+	if err := client.SendIncidentData(incidentData); err == nil {
+		t.Error("SendIncidentData with status 403 = nil, want an error")
+	}
+}
@@ -35,3 +35,27 @@ type IFMetricPostRequestPayload struct {
 	UserName   string                   `json:"userName" validate:"required"`
 	Data       MetricDataReceivePayload `json:"data" validate:"required"`
 }
+
+// LogData is a log entry of a LOG project. Tag is the instance name.
+type LogData struct {
+	TimeStamp     int64       `json:"timestamp" validate:"required"`
+	Tag           string      `json:"tag" validate:"required"`
+	ComponentName string      `json:"componentName,omitempty"`
+	Data          interface{} `json:"data" validate:"required"`
+}
+
+// IncidentData is an incident or alert of an INCIDENT or ALERT project.
+type IncidentData struct {
+	TimeStamp     int64       `json:"timestamp" validate:"required"`
+	InstanceName  string      `json:"instanceName" validate:"required"`
+	ComponentName string      `json:"componentName,omitempty"`
+	Data          interface{} `json:"data" validate:"required"`
+}
+
+// DeploymentData is a deployment or change event of a DEPLOYMENT project.
+type DeploymentData struct {
+	TimeStamp     int64       `json:"timestamp" validate:"required"`
+	InstanceName  string      `json:"instanceName" validate:"required"`
+	ComponentName string      `json:"componentName,omitempty"`
+	Data          interface{} `json:"data" validate:"required"`
+}
@@ -1,6 +1,7 @@
 package insightfinder
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/bigkevmcd/go-configparser"
 	"log/slog"
@@ -213,3 +214,29 @@ func FormCompleteURL(link string, endpoint string) string {
 	postUrl.Path = path.Join(postUrl.Path, endpoint)
 	return postUrl.String()
 }
+
+// ChunkRecords splits records into chunks whose JSON encoding stays below
+// limit bytes. A record larger than limit is sent in a chunk of its own.
+func ChunkRecords[T any](records []T, limit int) [][]T {
+	chunks := make([][]T, 0)
+	current := make([]T, 0)
+	currentSize := 0
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			slog.Error("Failed to encode record", "error", err)
+			continue
+		}
+		if len(current) > 0 && currentSize+len(data) > limit {
+			chunks = append(chunks, current)
+			current = make([]T, 0)
+			currentSize = 0
+		}
+		current = append(current, record)
+		currentSize += len(data)
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
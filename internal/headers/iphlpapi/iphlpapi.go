@@ -18,6 +18,7 @@ package iphlpapi
 import (
 	"encoding/binary"
 	"fmt"
+	"net/netip"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
@@ -60,6 +61,53 @@ func GetTCPConnectionStates(family uint32) (map[MIB_TCP_STATE]uint32, error) {
 	}
 }
 
+// GetTCPConnections returns every TCP connection and listener of the address
+// family together with the PID of the owning process.
+func GetTCPConnections(family uint32) ([]TCPConnection, error) {
+	switch family {
+	case windows.AF_INET:
+		table, err := getExtendedTcpTable[MIB_TCPROW_OWNER_PID](family, TCPTableOwnerPIDAll)
+		if err != nil {
+			return nil, err
+		}
+
+		connections := make([]TCPConnection, 0, len(table))
+		for _, row := range table {
+			connections = append(connections, TCPConnection{
+				State:      row.dwState,
+				LocalAddr:  row.dwLocalAddr.addr(),
+				LocalPort:  row.dwLocalPort.uint16(),
+				RemoteAddr: row.dwRemoteAddr.addr(),
+				RemotePort: row.dwRemotePort.uint16(),
+				OwningPID:  row.dwOwningPid,
+			})
+		}
+
+		return connections, nil
+	case windows.AF_INET6:
+		table, err := getExtendedTcpTable[MIB_TCP6ROW_OWNER_PID](family, TCPTableOwnerPIDAll)
+		if err != nil {
+			return nil, err
+		}
+
+		connections := make([]TCPConnection, 0, len(table))
+		for _, row := range table {
+			connections = append(connections, TCPConnection{
+				State:      row.dwState,
+				LocalAddr:  netip.AddrFrom16(row.ucLocalAddr),
+				LocalPort:  row.dwLocalPort.uint16(),
+				RemoteAddr: netip.AddrFrom16(row.ucRemoteAddr),
+				RemotePort: row.dwRemotePort.uint16(),
+				OwningPID:  row.dwOwningPid,
+			})
+		}
+
+		return connections, nil
+	default:
+		return nil, fmt.Errorf("unsupported address family %d", family)
+	}
+}
+
 func GetOwnerPIDOfTCPPort(family uint32, tcpPort uint16) (uint32, error) {
 	switch family {
 	case windows.AF_INET:
@@ -18,6 +18,7 @@ package iphlpapi
 import (
 	"encoding/binary"
 	"fmt"
+	"net/netip"
 )
 
 // MIB_TCPROW_OWNER_PID structure for IPv4.
@@ -48,6 +49,16 @@ type MIB_TCP6ROW_OWNER_PID struct {
 	dwOwningPid     uint32
 }
 
+// TCPConnection is a row of the TCP table with the PID of the owning process.
+type TCPConnection struct {
+	State      MIB_TCP_STATE
+	LocalAddr  netip.Addr
+	LocalPort  uint16
+	RemoteAddr netip.Addr
+	RemotePort uint16
+	OwningPID  uint32
+}
+
 type MIB_TCP_STATE uint32
 
 const (
@@ -105,3 +116,11 @@ func (b BigEndianUint32) uint16() uint16 {
 
 	return binary.LittleEndian.Uint16(data)
 }
+
+// addr returns the IPv4 address, which is stored in network byte order.
+func (b BigEndianUint32) addr() netip.Addr {
+	var data [4]byte
+	binary.LittleEndian.PutUint32(data[:], uint32(b))
+
+	return netip.AddrFrom4(data)
+}
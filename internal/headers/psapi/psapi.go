@@ -59,3 +59,34 @@ func GetPerformanceInfo() (PerformanceInformation, error) {
 
 	return lppi, nil
 }
+
+// ProcessMemoryCounters is a wrapper of the PROCESS_MEMORY_COUNTERS struct.
+// https://learn.microsoft.com/en-us/windows/win32/api/psapi/ns-psapi-process_memory_counters
+type ProcessMemoryCounters struct {
+	cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+//nolint:gochecknoglobals
+var procGetProcessMemoryInfo = psapi.NewProc("GetProcessMemoryInfo")
+
+// GetProcessMemoryInfo returns the memory counters of a process opened with
+// PROCESS_QUERY_LIMITED_INFORMATION.
+func GetProcessMemoryInfo(process windows.Handle) (ProcessMemoryCounters, error) {
+	var counters ProcessMemoryCounters
+	size := (uint32)(unsafe.Sizeof(counters))
+	counters.cb = size
+	r1, _, err := procGetProcessMemoryInfo.Call(uintptr(process), uintptr(unsafe.Pointer(&counters)), uintptr(size))
+	if r1 == 0 {
+		return ProcessMemoryCounters{}, err
+	}
+	return counters, nil
+}
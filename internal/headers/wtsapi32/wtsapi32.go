@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
@@ -86,6 +87,42 @@ type wtsSessionInfo1 struct {
 	pFarmName *uint16
 }
 
+// wtsSessionInfo is the WTS_INFO_CLASS value that returns a WTSINFOW structure.
+const wtsSessionInfo = 24
+
+// wtsInfo contains information about a Remote Desktop Services session.
+// docs: https://learn.microsoft.com/en-us/windows/win32/api/wtsapi32/ns-wtsapi32-wtsinfow
+type wtsInfo struct {
+	State                   uint32
+	SessionID               uint32
+	IncomingBytes           uint32
+	OutgoingBytes           uint32
+	IncomingFrames          uint32
+	OutgoingFrames          uint32
+	IncomingCompressedBytes uint32
+	OutgoingCompressedBytes uint32
+	WinStationName          [32]uint16
+	Domain                  [17]uint16
+	UserName                [21]uint16
+	// The LARGE_INTEGER fields below are 8-byte aligned on every architecture.
+	_              uint32
+	ConnectTime    int64
+	DisconnectTime int64
+	LastInputTime  int64
+	LogonTime      int64
+	CurrentTime    int64
+}
+
+// WTSSessionTimes holds the session timestamps. Times the session does not
+// report, such as LastInputTime for the local console, are zero.
+type WTSSessionTimes struct {
+	ConnectTime    time.Time
+	DisconnectTime time.Time
+	LastInputTime  time.Time
+	LogonTime      time.Time
+	CurrentTime    time.Time
+}
+
 type WTSSession struct {
 	ExecEnvID   uint32
 	State       WTSConnectState
@@ -105,6 +142,8 @@ var (
 	procWTSEnumerateSessionsEx = wtsapi32.NewProc("WTSEnumerateSessionsExW")
 	procWTSFreeMemoryEx        = wtsapi32.NewProc("WTSFreeMemoryExW")
 	procWTSCloseServer         = wtsapi32.NewProc("WTSCloseServer")
+	procWTSQuerySessionInfo    = wtsapi32.NewProc("WTSQuerySessionInformationW")
+	procWTSFreeMemory          = wtsapi32.NewProc("WTSFreeMemory")
 
 	WTSSessionStates = map[WTSConnectState]string{
 		wtsActive:       "active",
@@ -217,3 +256,45 @@ func WTSEnumerateSessionsEx(server windows.Handle, logger *slog.Logger) ([]WTSSe
 
 	return sessions, nil
 }
+
+// fileTimeToTime converts a FILETIME value stored in a LARGE_INTEGER.
+func fileTimeToTime(value int64) time.Time {
+	if value <= 0 {
+		return time.Time{}
+	}
+	fileTime := windows.Filetime{
+		LowDateTime:  uint32(value & 0xFFFFFFFF),
+		HighDateTime: uint32(value >> 32),
+	}
+	return time.Unix(0, fileTime.Nanoseconds())
+}
+
+func WTSQuerySessionTimes(server windows.Handle, sessionID uint32) (WTSSessionTimes, error) {
+	var info *wtsInfo
+
+	var bytesReturned uint32
+
+	r1, _, err := procWTSQuerySessionInfo.Call(
+		uintptr(server),
+		uintptr(sessionID),
+		uintptr(wtsSessionInfo),
+		uintptr(unsafe.Pointer(&info)),
+		uintptr(unsafe.Pointer(&bytesReturned)),
+	)
+	if r1 == 0 {
+		return WTSSessionTimes{}, fmt.Errorf("WTSQuerySessionInformation: %w", err)
+	}
+	defer procWTSFreeMemory.Call(uintptr(unsafe.Pointer(info))) //nolint:errcheck
+
+	if uintptr(bytesReturned) < unsafe.Sizeof(wtsInfo{}) {
+		return WTSSessionTimes{}, fmt.Errorf("WTSQuerySessionInformation returned %d bytes", bytesReturned)
+	}
+
+	return WTSSessionTimes{
+		ConnectTime:    fileTimeToTime(info.ConnectTime),
+		DisconnectTime: fileTimeToTime(info.DisconnectTime),
+		LastInputTime:  fileTimeToTime(info.LastInputTime),
+		LogonTime:      fileTimeToTime(info.LogonTime),
+		CurrentTime:    fileTimeToTime(info.CurrentTime),
+	}, nil
+}
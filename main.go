@@ -2,15 +2,27 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"if-win-dex-agent/cache"
 	"if-win-dex-agent/collector"
 	"if-win-dex-agent/insightfinder"
 	"if-win-dex-agent/tool"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
+// version is set at build time with -ldflags "-X main.version=<version>".
+var version = "dev"
+
 func main() {
+	configPath := flag.String("config", tool.DEFAULT_AGENT_CONFIG, "Path of the agent config file")
+	dryRun := flag.Bool("dry-run", false, "Collect once and print the data instead of sending it to InsightFinder")
+	flag.Parse()
+
 	cacheService, err := cache.CreateCacheService()
 	if err != nil {
 		slog.Error(err.Error())
@@ -21,64 +33,262 @@ func main() {
 		return
 	}
 
+	agentConfig := tool.LoadAgentConfig(*configPath)
+
+	// State that has to survive agent restarts. Collectors relying on it keep
+	// working without persistence when the file cannot be opened.
+	stateService, err := cache.CreateStateService(insightfinder.AbsFilePath(tool.DEFAULT_STATE_FILE))
+	if err != nil {
+		slog.Error(err.Error())
+	} else if *dryRun {
+		// A dry run must not record the boot, certificates or offsets it saw,
+		// or the running agent would not report them.
+		stateService.SetReadOnly()
+	}
+
 	// Init InsightFinder service
 	IFClient := insightfinder.CreateInsightFinderClient("https://app.insightfinder.com", "user", "", "Win-Dex-Agent")
+	logConfig := collector.LoadLogConfig(agentConfig)
+	logClient := insightfinder.CreateInsightFinderClient("https://app.insightfinder.com", "user", "", logConfig.Project)
+	eventConfig := collector.LoadEventConfig(agentConfig)
+	incidentClient := insightfinder.CreateInsightFinderClient("https://app.insightfinder.com", "user", "", eventConfig.IncidentProject)
+	deploymentClient := insightfinder.CreateInsightFinderClient("https://app.insightfinder.com", "user", "", eventConfig.DeploymentProject)
 
 	generalCollectorService := collector.CreateGeneralCollector()
 	pdhCollectorService := collector.NewPdhCollectorService()
+	processCollector := collector.NewProcessCollector(collector.LoadProcessConfig(agentConfig), stateService)
+	filesystemCollector := collector.NewFilesystemCollector(collector.LoadFilesystemConfig(agentConfig), cacheService)
+	inventoryCollector := collector.NewInventoryCollector(version)
+	uptimeCollector := collector.NewUptimeCollector(stateService)
+	sessionCollector := collector.NewSessionCollector(collector.LoadSessionConfig(agentConfig))
+	connectionCollector := collector.NewConnectionCollector()
+	networkInterfaceCollector := collector.NewNetworkInterfaceCollector()
+	httpProbeCollector := collector.NewHTTPProbeCollector(collector.LoadHTTPProbeConfig(agentConfig))
+	dnsProbeCollector := collector.NewDNSProbeCollector(collector.LoadDNSProbeConfig(agentConfig))
+	tcpProbeCollector := collector.NewTCPProbeCollector(collector.LoadTCPProbeConfig(agentConfig))
+	transactionCollector := collector.NewTransactionCollector(collector.LoadTransactionConfig(agentConfig))
+	certificateCollector := collector.NewCertificateCollector(collector.LoadCertificateConfig(agentConfig), stateService)
+	logCollector := collector.NewLogCollector(logConfig, stateService)
+	execCollector := collector.NewExecCollector(collector.LoadExecConfig(agentConfig))
+	prometheusCollector := collector.NewPrometheusCollector(collector.LoadPrometheusConfig(agentConfig))
+	pushCollector := collector.NewPushCollector(collector.LoadPushConfig(agentConfig))
 
-	for {
-		go func() {
-			startTime := time.Now()
-			slog.Log(context.Background(), slog.LevelInfo, "Start collecting metrics at", "time", startTime)
-			pdhCollectorService.Collect()
+	collectAndSend := func() {
+		startTime := time.Now()
+		slog.Log(context.Background(), slog.LevelInfo, "Start collecting metrics at", "time", startTime)
+		pdhCollectorService.Collect()
+		processCollector.Collect()
+		filesystemCollector.Collect()
+		inventoryCollector.Collect()
+		uptimeCollector.Collect()
+		sessionCollector.Collect()
+		connectionCollector.Collect()
+		networkInterfaceCollector.Collect()
+		httpProbeCollector.Collect()
+		dnsProbeCollector.Collect()
+		tcpProbeCollector.Collect()
+		transactionCollector.Collect()
+		certificateCollector.Collect()
+		logCollector.Collect()
+		execCollector.Collect()
+		prometheusCollector.Collect()
+		pushCollector.Collect()
+
+		events := make([]collector.Event, 0)
 
-			// Add metrics from generalCollectorService
-			for device, metrics := range *generalCollectorService.GetMemoryMetrics() {
-				for metric, value := range metrics {
-					cacheService.AddMetricRecord(device, metric, value)
-				}
+		// Add metrics from generalCollectorService
+		for device, metrics := range *generalCollectorService.GetMemoryMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *generalCollectorService.GetCPUMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *processCollector.GetProcessMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *processCollector.GetApplicationMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *processCollector.GetUserMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *processCollector.GetProcessTreeMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *processCollector.GetCrashLoopMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		events = append(events, processCollector.GetEvents()...)
+		for device, metrics := range *filesystemCollector.GetFilesystemMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		events = append(events, filesystemCollector.GetEvents()...)
+		events = append(events, inventoryCollector.GetEvents()...)
+		for device, metrics := range *uptimeCollector.GetUptimeMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		events = append(events, uptimeCollector.GetEvents()...)
+		for device, metrics := range *sessionCollector.GetSessionMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
 			}
-			for device, metrics := range *generalCollectorService.GetCPUMetrics() {
-				for metric, value := range metrics {
-					cacheService.AddMetricRecord(device, metric, value)
-				}
+		}
+		for device, metrics := range *connectionCollector.GetConnectionMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
 			}
-			for device, metrics := range *generalCollectorService.GetProcessMetrics() {
-				for metric, value := range metrics {
-					cacheService.AddMetricRecord(device, metric, value)
-				}
+		}
+		for device, metrics := range *generalCollectorService.GetNetworkMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
 			}
-			for device, metrics := range *generalCollectorService.GetNetworkMetrics() {
-				for metric, value := range metrics {
-					cacheService.AddMetricRecord(device, metric, value)
-				}
+		}
+		for device, metrics := range *networkInterfaceCollector.GetInterfaceMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
 			}
+		}
+		events = append(events, networkInterfaceCollector.GetEvents()...)
+		for device, metrics := range *httpProbeCollector.GetProbeMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *dnsProbeCollector.GetProbeMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *tcpProbeCollector.GetProbeMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *transactionCollector.GetTransactionMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		events = append(events, transactionCollector.GetEvents()...)
+		for device, metrics := range *certificateCollector.GetCertificateMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		events = append(events, certificateCollector.GetEvents()...)
+		for device, metrics := range *execCollector.GetExecMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *prometheusCollector.GetPrometheusMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *pushCollector.GetPushMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
 
-			// Add metrics from pdhCollectorService
-			for device, metrics := range *pdhCollectorService.GetThermalMetrics() {
-				for metric, value := range metrics {
-					cacheService.AddMetricRecord(device, metric, value)
-				}
+		// Add metrics from pdhCollectorService
+		for device, metrics := range *pdhCollectorService.GetThermalMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
+			}
+		}
+		for device, metrics := range *pdhCollectorService.GetMemoryMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
 			}
-			for device, metrics := range *pdhCollectorService.GetNetworkMetrics() {
-				for metric, value := range metrics {
-					cacheService.AddMetricRecord(device, metric, value)
-				}
+		}
+		for device, metrics := range *pdhCollectorService.GetNetworkMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
 			}
+		}
 
-			for device, metrics := range *pdhCollectorService.GetDiskMetrics() {
-				for metric, value := range metrics {
-					cacheService.AddMetricRecord(device, metric, value)
-				}
+		for device, metrics := range *pdhCollectorService.GetDiskMetrics() {
+			for metric, value := range metrics {
+				cacheService.AddMetricRecord(device, metric, value)
 			}
+		}
 
-			idm := tool.BuildIDMFromCache(startTime, "Win-Dex-Agent", cacheService)
+		idm := tool.BuildIDMFromCache(startTime, "Win-Dex-Agent", cacheService)
+		logData := tool.BuildLogData(logCollector.GetLogEntries(), "Win-Dex-Agent")
+		for _, event := range events {
+			slog.Info(event.Message, "type", event.Type, "device", event.Device, "time", event.Timestamp)
+		}
+		incidentData, deploymentData := tool.BuildEventData(events, "Win-Dex-Agent", eventConfig)
+		if *dryRun {
+			tool.PrintIDM(idm)
+			tool.PrintLogData(logData)
+			tool.PrintEventData(incidentData, deploymentData)
+			fmt.Print(processCollector.FormatProcessTree())
+		} else {
 			IFClient.SendMetricData(idm)
-			cacheService.ClearCache()
-			slog.Log(context.Background(), slog.LevelInfo, "End collecting metrics at", "time", time.Now())
-		}()
-		time.Sleep(5 * time.Minute)
+			// Log offsets are only saved once the entries read up to them
+			// were sent; otherwise the next collection reads them again.
+			if len(logData) == 0 || logClient.SendLogData(logData) == nil {
+				logCollector.CommitOffsets()
+			} else {
+				logCollector.RollbackOffsets()
+			}
+			// Events are not kept for a retry; a failed send is in the log.
+			if len(incidentData) > 0 {
+				_ = incidentClient.SendIncidentData(incidentData)
+			}
+			if len(deploymentData) > 0 {
+				_ = deploymentClient.SendDeploymentData(deploymentData)
+			}
+		}
+		cacheService.ClearCache()
+		slog.Log(context.Background(), slog.LevelInfo, "End collecting metrics at", "time", time.Now())
 	}
 
+	if *dryRun {
+		collectAndSend()
+		return
+	}
+
+	// Record a graceful shutdown so the next boot is reported as a clean reboot.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		slog.Info("Agent shutting down")
+		uptimeCollector.RecordShutdown()
+		os.Exit(0)
+	}()
+
+	tcpProbeCollector.Start()
+	pushCollector.Start()
+	// Collections run one at a time since the collectors keep state between
+	// them. A collection running past the interval delays the next one rather
+	// than overlapping it.
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		collectAndSend()
+		<-ticker.C
+	}
 }
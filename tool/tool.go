@@ -1,11 +1,46 @@
 package tool
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"if-win-dex-agent/cache"
+	"if-win-dex-agent/collector"
 	"if-win-dex-agent/insightfinder"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"time"
+
+	"github.com/bigkevmcd/go-configparser"
 )
 
+const DEFAULT_AGENT_CONFIG = "conf.d/config.ini"
+const DEFAULT_STATE_FILE = "agent_state.db"
+
+// LoadAgentConfig reads the agent configuration file. When the file does not
+// exist an empty configuration is returned so every collector uses its defaults.
+func LoadAgentConfig(configPath string) *configparser.ConfigParser {
+	if configPath == "" {
+		configPath = DEFAULT_AGENT_CONFIG
+	}
+	if !filepath.IsAbs(configPath) {
+		configPath = insightfinder.AbsFilePath(configPath)
+	}
+	if _, err := os.Stat(configPath); errors.Is(err, fs.ErrNotExist) {
+		slog.Info("No agent config file found, using defaults", "path", configPath)
+		return configparser.New()
+	}
+	p, err := configparser.NewConfigParserFromFile(configPath)
+	if err != nil {
+		slog.Error("Failed to parse agent config file", "path", configPath, "error", err)
+		return configparser.New()
+	}
+	slog.Info("Loaded agent config file", "path", configPath)
+	return p
+}
+
 func BuildIDMFromCache(timestamp time.Time, instanceName string, cache *cache.CacheService) *insightfinder.InstanceDataMap {
 	instanceDataMap := make(insightfinder.InstanceDataMap)
 	for _, deviceName := range *cache.ListDevices() {
@@ -35,3 +70,93 @@ func BuildIDMFromCache(timestamp time.Time, instanceName string, cache *cache.Ca
 	}
 	return &instanceDataMap
 }
+
+// PrintIDM writes the instance data map to stdout for dry runs.
+func PrintIDM(instanceDataMap *insightfinder.InstanceDataMap) {
+	data, err := json.MarshalIndent(instanceDataMap, "", "  ")
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	fmt.Println(string(data))
+}
+
+// BuildLogData converts log entries to the log data of a LOG project, naming
+// instances like BuildIDMFromCache does.
+func BuildLogData(entries []collector.LogEntry, instanceName string) []insightfinder.LogData {
+	logData := make([]insightfinder.LogData, 0, len(entries))
+	for _, entry := range entries {
+		tag := instanceName
+		if entry.Device != "" {
+			tag = entry.Device + "_" + instanceName
+		}
+		logData = append(logData, insightfinder.LogData{
+			TimeStamp:     entry.Timestamp.UnixMilli(),
+			Tag:           tag,
+			ComponentName: entry.Component,
+			Data:          entry.Message,
+		})
+	}
+	return logData
+}
+
+// PrintLogData writes log data to stdout for dry runs.
+func PrintLogData(logData []insightfinder.LogData) {
+	data, err := json.MarshalIndent(logData, "", "  ")
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	fmt.Println(string(data))
+}
+
+// BuildEventData splits collector events into incidents and change events as
+// set in the event config, naming instances like BuildIDMFromCache does. The
+// data of an event carries its type and message next to its own fields.
+// Events of neither kind are left out.
+func BuildEventData(events []collector.Event, instanceName string, config collector.EventConfig) ([]insightfinder.IncidentData, []insightfinder.DeploymentData) {
+	incidentData := make([]insightfinder.IncidentData, 0)
+	deploymentData := make([]insightfinder.DeploymentData, 0)
+	for _, event := range events {
+		combinedInstanceName := instanceName
+		if event.Device != "" {
+			combinedInstanceName = event.Device + "_" + instanceName
+		}
+		data := make(map[string]interface{}, len(event.Data)+2)
+		for key, value := range event.Data {
+			data[key] = value
+		}
+		data["type"] = event.Type
+		data["message"] = event.Message
+		switch {
+		case config.IsChangeEvent(event.Type):
+			deploymentData = append(deploymentData, insightfinder.DeploymentData{
+				TimeStamp:     event.Timestamp.UnixMilli(),
+				InstanceName:  combinedInstanceName,
+				ComponentName: instanceName,
+				Data:          data,
+			})
+		case config.IsIncidentEvent(event.Type):
+			incidentData = append(incidentData, insightfinder.IncidentData{
+				TimeStamp:     event.Timestamp.UnixMilli(),
+				InstanceName:  combinedInstanceName,
+				ComponentName: instanceName,
+				Data:          data,
+			})
+		}
+	}
+	return incidentData, deploymentData
+}
+
+// PrintEventData writes incident and deployment data to stdout for dry runs.
+func PrintEventData(incidentData []insightfinder.IncidentData, deploymentData []insightfinder.DeploymentData) {
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"incidents":   incidentData,
+		"deployments": deploymentData,
+	}, "", "  ")
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	fmt.Println(string(data))
+}